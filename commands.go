@@ -0,0 +1,137 @@
+package iguana
+
+import (
+    "strconv"
+    "strings"
+)
+
+func (cv *converter) detect_ai_command(c Command) bool {
+    // Checks if the given input command is a WinMUGEN-style AI command
+    // WinMUGEN did not yet include a trigger for checking if a character was AI-controlled,
+    // so a workaround many authors used was to make commands impossible for a human to input,
+    // the idea being that MUGEN's AI could just "activate" it on a whim without actually inputting the command
+
+    // tokenizes the input so 1 input = 1 character
+    // we also strip out any formatting/padding
+    cmd_str := cv.tokenize(c.command)
+    cmd_str = charge_regex.ReplaceAllString(cmd_str, "|")
+    cmd_str = strings.ReplaceAll(cmd_str, "-", "")
+    cmd_str = strings.ReplaceAll(cmd_str, "/", "")
+    cmd_str = strings.ReplaceAll(cmd_str, "+", "")
+
+    // the length of this string should be roughly equal to the number of inputs the game has to detect for this command
+    button_count := len(cmd_str)
+
+    // a zero-time command is physically impossible to do
+    if (c.time == 0) {return true}
+
+    // checks if there's more buttons to press than the number of frames where inputs are read as part of a command
+    if (c.time < button_count) {return true}
+
+    return false
+}
+
+func (cv *converter) scrape_remap(input *ini_document) map[string]string {
+    // Returns a map of remapped buttons created from the given INI's [Remap] section
+    // MUGEN's remap format is "old_button = new_button", meaning that pressing old_button acts as new_button
+    // commands are written using the *new* buttons, so we invert the map here to find out what the player actually presses
+
+    remap := make(map[string]string)
+
+    for _, section := range input.sections {
+        if section.is("Remap") {
+            for _, key := range section.keys {
+                var key_name = strings.ToLower(key.name)
+                var key_value = strings.ToLower(key.value)
+
+                // a blank value means the button is disabled entirely, so there's nothing to map back to it
+                if key_value == "" {continue}
+
+                // if several buttons end up acting as the same one, prefer the button that maps to itself, then whichever came first
+                if _, exists := remap[key_value]; exists && key_name != key_value {continue}
+
+                remap[key_value] = key_name
+
+                cv.debug("Found button remap:", key_name, "->", key_value)
+            }
+        }
+
+        // Remap is always defined before any commands, so there's no need to read any further than this
+        if section.is("Statedef -1") {
+            break
+        }
+    }
+
+    return remap
+}
+
+func apply_remap(input string, remap map[string]string) string {
+    // Swaps every button in the given command string for the one the player physically presses
+    // this has to be done one character at a time, otherwise swapping two buttons would just overwrite one of them
+    // (buttons are always lowercase and directions are always uppercase, so there's no risk of mixing them up)
+
+    if len(remap) == 0 {
+        return input
+    }
+
+    var output string
+
+    for _, i := range input {
+        if button, exists := remap[string(i)]; exists {
+            output += button
+        } else {
+            output += string(i)
+        }
+    }
+
+    return output
+}
+
+func (cv *converter) scrape_commands(input *ini_document) []Command {
+    // Returns array of command-structs created from the given INI
+    // This should *only* parse sections named "Command" (case insensitive)
+
+    cv.debug("Scraping command entries...", "\n"+hr)
+    var cmdlist []Command
+    var default_cmd_time int = 15
+
+    for _, section := range input.sections {
+        // check for Defaults section and get default command time
+        if section.is("Defaults") {
+            for _, key := range section.keys {
+                if strings.EqualFold(key.name, "command.time") {
+                    default_cmd_time, _ = strconv.Atoi(key.value)
+                }
+            }
+        }
+
+        if section.is("Command") {
+            var cmd Command
+
+            cmd.time = default_cmd_time
+            cmd.source = Source{File: input.path, Line: section.line, Section: section.name}
+
+            // only the first of each key counts, in case one's been written twice
+            cmd.name = section.value("name")
+
+            // strip out all spaces from the command internally, done to make comparisons easier (see assemble_move_table())
+            cmd.command = strings.ReplaceAll(section.value("command"), " ", "")
+
+            // amount of time the command must be performed in, used to check for possible AI commands
+            if time := section.value("time"); time != "" {
+                cmd.time, _ = strconv.Atoi(time)
+            }
+
+            cv.debug("Found command:", cmd)
+            cmdlist = append(cmdlist, cmd)
+        }
+
+        // Statedef -1 marks the end of [Command] blocks, so once we reach that, end the loop
+        if section.is("Statedef -1") {
+            cv.debug("Reached Statedef -1. Ending command scraping...")
+            break
+        }
+    }
+
+    return cmdlist
+}
@@ -0,0 +1,144 @@
+package iguana
+
+import (
+    "encoding/json"
+    "os"
+    "path/filepath"
+    "strings"
+    "gopkg.in/ini.v1"
+)
+
+// how many folders above a character find_common_commands will look through, for characters that aren't inside of a chars folder
+const max_common_depth = 6
+
+// FindCommonCommands looks for the common command files of the Ikemen GO install the given folder is in
+// it searches the folder and the folders above it, up to the install's root, and returns nothing if no install is found
+func FindCommonCommands(dir string) []string {
+    return find_common_commands(dir)
+}
+
+func find_common_commands(dir string) []string {
+    // Ikemen GO can share commands between every character through common.cmd (set in its config as CommonCmd, or [Common] Cmd)
+    // characters are usually somewhere inside the engine's folder (e.g. chars/kfm/kfm.def), so we go up from there until we find it
+    // the folder holding chars is the engine's root, so the search stops there instead of carrying on through the rest of the disk
+
+    dir, err := filepath.Abs(dir)
+    if err != nil {
+        return nil
+    }
+
+    at_root := false
+
+    for depth := 0; depth <= max_common_depth; depth++ {
+        if files := engine_common_commands(dir); len(files) > 0 {
+            return files
+        }
+
+        parent := filepath.Dir(dir)
+        if at_root || parent == dir {
+            return nil
+        }
+
+        at_root = strings.EqualFold(filepath.Base(dir), "chars")
+        dir = parent
+    }
+
+    return nil
+}
+
+func engine_common_commands(root string) []string {
+    // Returns the common command files of an Ikemen GO install at the given folder, if there is one
+    // newer versions keep their config in save/config.ini, older ones in save/config.json, and both fall back to data/common.cmd
+
+    var files []string
+
+    if file_data, err := os.ReadFile(filepath.Join(root, "save", "config.ini")); err == nil {
+        if parsed_ini, err := ini.LoadSources(ini.LoadOptions{AllowNonUniqueSections: true, SkipUnrecognizableLines: true}, file_data); err == nil {
+            for _, section := range parsed_ini.Sections() {
+                if !strings.EqualFold(section.Name(), "Common") {continue}
+                files = append(files, strings.Split(key_value(section, "Cmd"), ",")...)
+            }
+        }
+    } else if file_data, err := os.ReadFile(filepath.Join(root, "save", "config.json")); err == nil {
+        var config map[string]interface{}
+
+        if json.Unmarshal(file_data, &config) == nil {
+            switch value := config["CommonCmd"].(type) {
+            case string:
+                files = append(files, value)
+            case []interface{}:
+                for _, file := range value {
+                    if name, ok := file.(string); ok {
+                        files = append(files, name)
+                    }
+                }
+            }
+        }
+    }
+
+    if len(files) == 0 {
+        files = []string{filepath.Join("data", "common.cmd")}
+    }
+
+    // only keep the files that actually exist, which is also what tells us this folder really is an install
+    var output []string
+
+    for _, file := range files {
+        file = strings.TrimSpace(file)
+        if file == "" {continue}
+
+        path := filepath.Join(root, file)
+        if info, err := os.Stat(path); err == nil && !info.IsDir() {
+            output = append(output, path)
+        }
+    }
+
+    return output
+}
+
+func (cv *converter) scrape_common_commands(files []string, commands []Command) []Command {
+    // Returns the commands from the given common command files that the character doesn't already define itself
+    // the character's own commands take priority, so a shared command never adds inputs to one it redefines
+
+    var output []Command
+
+    for _, path := range files {
+        // a missing common file shouldn't stop the movelist from being made, it'll just be missing the moves that use it
+        file_data, err := read_file(path)
+        if err != nil {
+            cv.debug("Couldn't read common command file:", err)
+            continue
+        }
+
+        cv.debug("Reading common commands from", path)
+
+        for _, command := range cv.scrape_commands(parse_mugen_ini(path, file_data)) {
+            if !has_command(commands, command.name) {
+                output = append(output, command)
+            }
+        }
+    }
+
+    return output
+}
+
+func has_command(commands []Command, name string) bool {
+    for _, c := range commands {
+        if c.name == name {
+            return true
+        }
+    }
+    return false
+}
+
+func key_value(section *ini.Section, name string) string {
+    // Returns the value of the given key in a section, ignoring case (or an empty string if there's no such key)
+
+    for _, key_name := range section.KeyStrings() {
+        if strings.EqualFold(key_name, name) {
+            return section.Key(key_name).String()
+        }
+    }
+
+    return ""
+}
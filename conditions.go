@@ -0,0 +1,284 @@
+package iguana
+
+import (
+    "math"
+    "strconv"
+    "strings"
+)
+
+// the values statetype can be compared against, in the order their bits are stored in a statetype mask
+var statetype_values = []string{"S", "C", "A", "L"}
+
+// the values ctrl can have; anything but 0 counts as having control
+var ctrl_values = []string{"1", "0"}
+
+// statetype masks for the groups of statetypes a move can be limited to
+var (
+    statetypes_all    = 1<<len(statetype_values) - 1
+    statetypes_air    = 1 << 2
+    statetypes_ground = 1<<0 | 1<<1
+)
+
+func (e *expr) possible_values(trigger string, domain []string) (int, int) {
+    // Works out which values of the given trigger (e.g. statetype) this expression could be true for, and which ones it could be false for
+    // both are returned as bitmasks over the domain, since an expression that doesn't involve the trigger at all can be either
+
+    all := 1<<len(domain) - 1
+
+    switch e.kind {
+    case expr_ident:
+        // a bare trigger is true whenever it's not zero, e.g. "trigger1 = ctrl"
+        if strings.EqualFold(e.value, trigger) {
+            mask := domain_mask(domain, func(value string) bool {return value != "0"})
+            return mask, all &^ mask
+        }
+
+    case expr_unary:
+        if e.value == "!" {
+            can_be_true, can_be_false := e.args[0].possible_values(trigger, domain)
+            return can_be_false, can_be_true
+        }
+
+    case expr_binary:
+        switch e.value {
+        case "&&":
+            left_true, left_false := e.args[0].possible_values(trigger, domain)
+            right_true, right_false := e.args[1].possible_values(trigger, domain)
+            return left_true & right_true, left_false | right_false
+
+        case "||":
+            left_true, left_false := e.args[0].possible_values(trigger, domain)
+            right_true, right_false := e.args[1].possible_values(trigger, domain)
+            return left_true | right_true, left_false & right_false
+
+        case "=", "!=":
+            _, right, ok := e.normalize_comparison(trigger)
+
+            if ok && (right.kind == expr_ident || right.kind == expr_number || right.kind == expr_range) {
+                mask := domain_mask(domain, func(value string) bool {return right.matches(value)})
+
+                if e.value == "!=" {
+                    return all &^ mask, mask
+                }
+                return mask, all &^ mask
+            }
+        }
+    }
+
+    return all, all
+}
+
+func (e *expr) matches(value string) bool {
+    // Checks if the given value is equal to this node, or inside of it if it's a range like [1000, 1010]
+
+    if e.kind != expr_range {
+        return strings.EqualFold(value, e.value)
+    }
+
+    number, err := strconv.ParseFloat(value, 64)
+    if err != nil || e.args[0].kind != expr_number || e.args[1].kind != expr_number {
+        return false
+    }
+
+    low, _ := strconv.ParseFloat(e.args[0].value, 64)
+    high, _ := strconv.ParseFloat(e.args[1].value, 64)
+
+    // square brackets include the number they're next to, parentheses don't
+    above_low := number > low || (number == low && e.value[0] == '[')
+    below_high := number < high || (number == high && e.value[1] == ']')
+
+    return above_low && below_high
+}
+
+func domain_mask(domain []string, include func(string) bool) int {
+    // Returns a bitmask of every value in the domain the given function agrees with
+
+    mask := 0
+
+    for i, value := range domain {
+        if include(value) {
+            mask |= 1 << i
+        }
+    }
+
+    return mask
+}
+
+func (move *Move) possible_values(trigger string, domain []string) int {
+    // Returns a bitmask of every value of the given trigger this move can be done with
+    // like with commands, every triggerall line has to be true, along with at least one numbered trigger group
+
+    mask := 1<<len(domain) - 1
+
+    for _, tree := range move.triggerall {
+        can_be_true, _ := tree.possible_values(trigger, domain)
+        mask &= can_be_true
+    }
+
+    groups := move.active_groups()
+
+    if len(groups) > 0 {
+        group_masks := 0
+
+        for _, group := range groups {
+            group_mask := 1<<len(domain) - 1
+
+            for _, tree := range group.lines {
+                can_be_true, _ := tree.possible_values(trigger, domain)
+                group_mask &= can_be_true
+            }
+
+            group_masks |= group_mask
+        }
+
+        mask &= group_masks
+    }
+
+    return mask
+}
+
+func statetype_label(mask int) string {
+    // Describes a statetype mask as either air-only, ground-only, or neither (an empty string)
+
+    if mask == 0 || mask == statetypes_all {
+        return ""
+    }
+
+    if mask &^ statetypes_air == 0 {
+        return "Air"
+    }
+
+    // lying down isn't something a move is ever really done from, so it doesn't count against being ground-only
+    if mask & statetypes_air == 0 && mask & statetypes_ground != 0 {
+        return "Ground"
+    }
+
+    return ""
+}
+
+func (move *Move) gated_by(state string) bool {
+    // Checks if this move can only be done while in (or right after) the given state, like the second hit of a rekka
+    // the empty string stands in for every other state here, so a move that can also be done from those isn't gated
+
+    for _, trigger := range []string{"stateno", "prevstateno"} {
+        if move.possible_values(trigger, []string{state, ""}) == 1 {
+            return true
+        }
+    }
+
+    return false
+}
+
+// comparison operators turned into their opposites, for comparisons that have been negated with !
+var negated_comparisons = map[string]string{">=": "<", ">": "<=", "<=": ">", "<": ">=", "=": "!=", "!=": "="}
+
+func (e *expr) power_requirement(negated bool, known map[string]float64) int {
+    // Works out the least amount of power needed for this expression to be true (or false, if negated)
+    // e.g. "power > 999" needs 1000, "power = [1000, 2999]" needs 1000, and "power < 1000" doesn't need any
+    // the known map fills in triggers that aren't numbers, but are still known ahead of time (like the character's max power)
+
+    switch e.kind {
+    case expr_unary:
+        if e.value == "!" {
+            return e.args[0].power_requirement(!negated, known)
+        }
+
+    case expr_binary:
+        left := e.args[0].power_requirement(negated, known)
+        right := e.args[1].power_requirement(negated, known)
+
+        // (following De Morgan's laws, a negated && acts like ||, and vice versa)
+        if (e.value == "&&" && !negated) || (e.value == "||" && negated) {
+            if left > right {return left}
+            return right
+        }
+
+        if (e.value == "||" && !negated) || (e.value == "&&" && negated) {
+            if left < right {return left}
+            return right
+        }
+
+        if _, ok := negated_comparisons[e.value]; ok {
+            return e.power_comparison(negated, known)
+        }
+    }
+
+    return 0
+}
+
+func (e *expr) power_comparison(negated bool, known map[string]float64) int {
+    // Works out the least amount of power needed for a single comparison against power to be true
+
+    op, other_side, ok := e.normalize_comparison("power")
+    if !ok {
+        return 0
+    }
+
+    if negated {
+        op = negated_comparisons[op]
+    }
+
+    // ranges only need as much power as their lower end (or one more, if it's excluded)
+    if other_side.kind == expr_range {
+        if op != "=" {return 0}
+
+        low, ok := other_side.args[0].constant_with(known)
+        if !ok {return 0}
+
+        if other_side.value[0] == '(' {
+            return int(math.Floor(low)) + 1
+        }
+        return int(math.Ceil(low))
+    }
+
+    amount, ok := other_side.constant_with(known)
+    if !ok || amount < 0 {
+        return 0
+    }
+
+    switch op {
+    case ">=", "=":
+        return int(math.Ceil(amount))
+    case ">":
+        return int(math.Floor(amount)) + 1
+    }
+
+    // <, <= and != don't need any power at all
+    return 0
+}
+
+func (move *Move) power_requirement(known map[string]float64) int {
+    // Works out the least amount of power needed to do this move
+    // every triggerall line has to be true, along with the cheapest of the numbered trigger groups
+
+    requirement := 0
+
+    for _, tree := range move.triggerall {
+        if amount := tree.power_requirement(false, known); amount > requirement {
+            requirement = amount
+        }
+    }
+
+    groups := move.active_groups()
+    cheapest_group := -1
+
+    for _, group := range groups {
+        group_requirement := 0
+
+        for _, tree := range group.lines {
+            if amount := tree.power_requirement(false, known); amount > group_requirement {
+                group_requirement = amount
+            }
+        }
+
+        if cheapest_group == -1 || group_requirement < cheapest_group {
+            cheapest_group = group_requirement
+        }
+    }
+
+    if cheapest_group > requirement {
+        requirement = cheapest_group
+    }
+
+    return requirement
+}
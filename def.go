@@ -0,0 +1,421 @@
+package iguana
+
+import (
+    "os"
+    "regexp"
+    "strings"
+    "gopkg.in/ini.v1"
+    "path/filepath"
+)
+
+// CmdFromDef gets a path to a command file from a given .def
+// a .def without a command file is reported as a MissingCmdError
+func CmdFromDef(def string) (string, error) {
+    return get_cmd_from_def(def)
+}
+
+// LoadDef gets the paths to a character's command and state files from a given .def
+// a .def without a command file is reported as a MissingCmdError
+func LoadDef(def string) (Character, error) {
+    return load_def(def, "")
+}
+
+// LoadDefLanguage is like LoadDef, but prefers the files the .def gives for the given language (e.g. "ja"),
+// either as prefixed keys like ja.cmd, or in a section of their own like [ja.Files]
+func LoadDefLanguage(def string, lang string) (Character, error) {
+    return load_def(def, lang)
+}
+
+// DefLanguages returns every language the given .def has files of its own for, in the order they're first found
+func DefLanguages(def string) ([]string, error) {
+    parsed_ini, err := read_def(def)
+    if err != nil {
+        return nil, err
+    }
+
+    return def_languages(parsed_ini), nil
+}
+
+// PatchOptions controls how a .def gets patched
+type PatchOptions struct {
+    Language string // patch the movelist of this language (e.g. ja.movelist) instead of the regular one
+    Backup   bool   // save the original .def as a .def.bak before changing it, unless there's a .def.bak already
+}
+
+// PatchDef patches the given .def to include a movelist with the given filename
+// an existing movelist key is pointed at the new file, and the rest of the .def is kept exactly as it was
+// it returns false if the .def had nothing to patch (no command file, or already using the movelist)
+func PatchDef(def string, output_file string, opt PatchOptions) (bool, error) {
+    return patch_def(def, output_file, opt)
+}
+
+// PreviewPatchDef returns what PatchDef would change the given .def to, without writing anything
+// the returned bool is false if it wouldn't change at all
+func PreviewPatchDef(def string, output_file string, opt PatchOptions) ([]byte, bool, error) {
+    file_data, err := read_file(def)
+    if err != nil {
+        return nil, false, err
+    }
+
+    return patch_def_data(def, file_data, output_file, opt.Language)
+}
+
+func get_cmd_from_def(input string) (string, error) {
+    // gets a path to a command file from a given .def
+    // the .def file is the "root" of a character, and among its data is the path to the command file
+    // so when Iguana is given a .def, we try to use what the .def says is the command file
+
+    char, err := load_def(input, "")
+    if err != nil {
+        return "", err
+    }
+
+    return char.Cmd, nil
+}
+
+func read_def(input string) (*ini.File, error) {
+    // loads a DEF file and parses its INI data
+
+    file_data, err := read_file(input)
+    if err != nil {
+        return nil, err
+    }
+
+    return load_ini(input, file_data, ini.LoadOptions{AllowNonUniqueSections: true, SkipUnrecognizableLines: true})
+}
+
+func split_language(name string) (string, string) {
+    // splits a language prefix off of a key or section name, e.g. "ja.cmd" into "ja" and "cmd"
+    // names without a prefix come back with an empty language
+
+    if i := strings.Index(name, "."); i > 0 {
+        return strings.ToLower(strings.TrimSpace(name[:i])), strings.TrimSpace(name[i+1:])
+    }
+
+    return "", strings.TrimSpace(name)
+}
+
+func def_files(parsed_ini *ini.File, lang string) [][2]string {
+    // returns the keys of a .def's [Files] section as name and value pairs, in order, with the names in lowercase
+    // Ikemen GO lets a .def give other files for each language, so keys for the given language replace the ones they localize
+    // (and keys for any other language are left out)
+
+    var files [][2]string
+    localized := make(map[string]string)
+    var localized_order []string
+
+    for _, section := range parsed_ini.Sections() {
+        section_lang, section_name := split_language(section.Name())
+        if !strings.EqualFold(section_name, "Files") {continue}
+        if section_lang != "" && section_lang != strings.ToLower(lang) {continue}
+
+        for _, key_name := range section.KeyStrings() {
+            key_lang, name := split_language(key_name)
+            value := section.Key(key_name).String()
+            name = strings.ToLower(name)
+
+            // a [ja.Files] section counts as having every key prefixed
+            if section_lang != "" {
+                key_lang = section_lang
+            }
+
+            if key_lang == "" {
+                files = append(files, [2]string{name, value})
+                continue
+            }
+
+            if key_lang == strings.ToLower(lang) {
+                if _, ok := localized[name]; !ok {
+                    localized_order = append(localized_order, name)
+                }
+                localized[name] = value
+            }
+        }
+    }
+
+    // replace the keys that have been localized, and add any that only exist for the language
+    for f := range files {
+        if value, ok := localized[files[f][0]]; ok {
+            files[f][1] = value
+            delete(localized, files[f][0])
+        }
+    }
+
+    for _, name := range localized_order {
+        if value, ok := localized[name]; ok {
+            files = append(files, [2]string{name, value})
+        }
+    }
+
+    return files
+}
+
+func def_languages(parsed_ini *ini.File) []string {
+    // returns every language a .def's [Files] sections have keys (or sections) for
+
+    var languages []string
+
+    for _, section := range parsed_ini.Sections() {
+        section_lang, section_name := split_language(section.Name())
+        if !strings.EqualFold(section_name, "Files") {continue}
+
+        if section_lang != "" && !contains_string(languages, section_lang) {
+            languages = append(languages, section_lang)
+        }
+
+        for _, key_name := range section.KeyStrings() {
+            if key_lang, _ := split_language(key_name); key_lang != "" && !contains_string(languages, key_lang) {
+                languages = append(languages, key_lang)
+            }
+        }
+    }
+
+    return languages
+}
+
+func load_def(input string, lang string) (Character, error) {
+    // gets the paths to every file Iguana can use from a given .def, preferring the given language's files if it has any
+    // besides the command file, this includes the state files (st, st0-st9 and stcommon), where move costs are found
+    // as well as the constants file (cns), where the character's max power is found, and Ikemen GO's common command files
+
+    char := Character{Def: input, Language: lang}
+
+    parsed_ini, err := read_def(input)
+    if err != nil {
+        return char, err
+    }
+
+    state_key_regex, _ := regexp.Compile("^(st[0-9]*|stcommon)$")
+
+    for _, file := range def_files(parsed_ini, lang) {
+        var key_name, key_value = file[0], file[1]
+
+        // note the Join() here; we convert the paths to absolutes to prevent ambiguity
+        // get the value of cmd and use it as the input file
+        if key_name == "cmd" && char.Cmd == "" {
+            char.Cmd = filepath.Join(filepath.Dir(input), key_value)
+        }
+
+        if key_name == "commoncmd" && key_value != "" {
+            char.Common = append(char.Common, filepath.Join(filepath.Dir(input), key_value))
+        }
+
+        if key_name == "cns" && key_value != "" {
+            char.Constants = filepath.Join(filepath.Dir(input), key_value)
+        }
+
+        if key_name == "movelist" && key_value != "" {
+            char.Movelist = filepath.Join(filepath.Dir(input), key_value)
+        }
+
+        if state_key_regex.MatchString(key_name) && key_value != "" {
+            char.States = append(char.States, filepath.Join(filepath.Dir(input), key_value))
+        }
+    }
+
+    if char.Cmd == "" {
+        return char, &MissingCmdError{Def: input}
+    }
+
+    // characters that don't name their common commands use the ones of the Ikemen GO install they're in, if any
+    if len(char.Common) == 0 {
+        char.Common = find_common_commands(filepath.Dir(input))
+    }
+
+    return char, nil
+}
+
+func patch_def(def string, output_file string, opt PatchOptions) (bool, error) {
+    // loads a given def and patches it to include a movelist.dat, only writing it if something actually changed
+
+    file_data, err := read_file(def)
+    if err != nil {
+        return false, err
+    }
+
+    patched, changed, err := patch_def_data(def, file_data, output_file, opt.Language)
+    if err != nil || !changed {
+        return false, err
+    }
+
+    if opt.Backup {
+        if err := write_backup(def + ".bak", file_data); err != nil {
+            return false, err
+        }
+    }
+
+    return true, write_file_atomic(def, patched)
+}
+
+func patch_def_data(def string, file_data []byte, output_file string, lang string) ([]byte, bool, error) {
+    // returns the given .def data with its movelist key set, and whether that changed anything
+    // this runs under the assumption that the .cmd specified by the file is *also* the location where the movelist is located
+    // (which via Iguana is always the case); when given a language, it's that language's .cmd and movelist instead
+    // everything else in the file (comments, spacing, line endings, a byte order mark) is left exactly as it was
+
+    parsed_ini, err := load_ini(def, file_data, ini.LoadOptions{AllowNonUniqueSections: true, SkipUnrecognizableLines: true})
+    if err != nil {
+        return nil, false, err
+    }
+
+    var cmd_value string
+    for _, file := range def_files(parsed_ini, lang) {
+        if file[0] == "cmd" && cmd_value == "" {
+            cmd_value = file[1]
+        }
+    }
+
+    if cmd_value == "" {
+        return file_data, false, nil
+    }
+
+    // strip the cmd value to just the path, and then add the output filename to it
+    dat_value := filepath.Join(filepath.Dir(cmd_value), output_file)
+
+    // a language's movelist goes in its own [ja.Files] section if it has one, and is written as ja.movelist in [Files] if not
+    // (only a movelist for this exact language counts, since the one without a language is what every other language falls back on)
+    var target *ini_section
+    var existing *ini_key
+    lang = strings.ToLower(lang)
+
+    for _, section := range parse_mugen_ini(def, file_data).sections {
+        section_lang, section_name := split_language(section.name)
+        if !strings.EqualFold(section_name, "Files") {continue}
+
+        switch {
+        case lang != "" && section_lang == lang && (target == nil || !strings.EqualFold(target.name, lang + ".Files")):
+            target = section
+        case section_lang == "" && target == nil:
+            target = section
+        }
+
+        for k, key := range section.keys {
+            key_lang, key_name := split_language(key.name)
+            if section_lang != "" {
+                key_lang = section_lang
+            }
+
+            if key_lang == lang && strings.EqualFold(key_name, "movelist") && existing == nil {
+                existing = &section.keys[k]
+            }
+        }
+    }
+
+    if target == nil {
+        return file_data, false, nil
+    }
+
+    // split the file up without losing any of its line endings, and use the same ones for anything we add
+    lines := strings.SplitAfter(string(file_data), "\n")
+    newline := "\n"
+    if strings.Contains(string(file_data), "\r\n") {
+        newline = "\r\n"
+    }
+
+    if existing != nil {
+        if same_path(existing.value, dat_value) {
+            return file_data, false, nil
+        }
+
+        // keep the key written however it was, along with any comment after it
+        line := lines[existing.line - 1]
+        content := strings.TrimRight(line, "\r\n")
+        code, comment := strip_comment(content)
+        code = code[:strings.Index(code, "=") + 1] + " " + dat_value
+        if comment != "" {
+            code += " " + comment
+        }
+
+        lines[existing.line - 1] = code + line[len(content):]
+        return []byte(strings.Join(lines, "")), true, nil
+    }
+
+    key_name := "movelist"
+    if lang != "" && !strings.EqualFold(target.name, lang + ".Files") {
+        key_name = lang + ".movelist"
+    }
+
+    // the new key goes right after the last one in the section, so it doesn't end up after any blank lines or comments
+    insert_at := target.line
+    if len(target.keys) > 0 {
+        insert_at = target.keys[len(target.keys) - 1].line
+    }
+
+    if !strings.HasSuffix(lines[insert_at - 1], "\n") {
+        lines[insert_at - 1] += newline
+    }
+
+    new_line := key_name + " = " + dat_value + newline
+    lines = append(lines[:insert_at], append([]string{new_line}, lines[insert_at:]...)...)
+
+    return []byte(strings.Join(lines, "")), true, nil
+}
+
+func same_path(a string, b string) bool {
+    // checks if two paths from a .def point to the same file, regardless of slashes or case (since MUGEN started out on Windows)
+
+    clean := func(path string) string {
+        return filepath.ToSlash(filepath.Clean(strings.TrimSpace(strings.ReplaceAll(path, "\\", "/"))))
+    }
+
+    return strings.EqualFold(clean(a), clean(b))
+}
+
+func write_backup(path string, data []byte) error {
+    // saves a copy of a file before it's changed, unless there's one already
+    // patching the same .def more than once (like once for every language) would otherwise replace the original with a patched one
+
+    file, err := os.OpenFile(path, os.O_WRONLY | os.O_CREATE | os.O_EXCL, 0666)
+    if os.IsExist(err) {
+        return nil
+    } else if err != nil {
+        return err
+    }
+
+    _, err = file.Write(data)
+    if close_err := file.Close(); err == nil {
+        err = close_err
+    }
+
+    // a half-written backup would be kept over the real one from then on
+    if err != nil {
+        os.Remove(path)
+    }
+
+    return err
+}
+
+func write_file_atomic(path string, data []byte) error {
+    // writes a file by saving it somewhere else first and then moving it into place,
+    // so that a failure halfway through never leaves behind a half-written file
+
+    mode := os.FileMode(0666)
+    if info, err := os.Stat(path); err == nil {
+        mode = info.Mode().Perm()
+    }
+
+    temp, err := os.CreateTemp(filepath.Dir(path), "." + filepath.Base(path) + ".*.tmp")
+    if err != nil {
+        return err
+    }
+
+    _, err = temp.Write(data)
+    if err == nil {
+        err = temp.Chmod(mode)
+    }
+    if close_err := temp.Close(); err == nil {
+        err = close_err
+    }
+
+    if err != nil {
+        os.Remove(temp.Name())
+        return err
+    }
+
+    if err := os.Rename(temp.Name(), path); err != nil {
+        os.Remove(temp.Name())
+        return err
+    }
+
+    return nil
+}
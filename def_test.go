@@ -0,0 +1,105 @@
+package iguana
+
+import (
+    "os"
+    "path/filepath"
+    "testing"
+)
+
+func TestPatchDefBackup(t *testing.T) {
+    // patching the same .def for several languages should leave the original in the backup, not the first patch
+    dir := t.TempDir()
+    def := filepath.Join(dir, "kfm.def")
+    original := "[Files]\ncmd = kfm.cmd\nja.cmd = ja.cmd\n"
+
+    if err := os.WriteFile(def, []byte(original), 0644); err != nil {
+        t.Fatal(err)
+    }
+
+    for _, lang := range []string{"", "ja"} {
+        if _, err := patch_def(def, "movelist.dat", PatchOptions{Language: lang, Backup: true}); err != nil {
+            t.Fatal(err)
+        }
+    }
+
+    if got, err := os.ReadFile(def + ".bak"); err != nil || string(got) != original {
+        t.Errorf("got backup:\n%s\nwant:\n%s\n(error: %v)", got, original, err)
+    }
+}
+
+func TestPatchDefData(t *testing.T) {
+    tests := []struct {
+        name string
+        lang string
+        def  string
+        want string
+    }{
+        {
+            "added after the last key",
+            "",
+            "[Files]\ncmd = kfm.cmd\nst = kfm.cns\n\n[Arcade]\n",
+            "[Files]\ncmd = kfm.cmd\nst = kfm.cns\nmovelist = movelist.dat\n\n[Arcade]\n",
+        },
+        {
+            "comment after the section, crlf and a byte order mark",
+            "",
+            "\ufeff[files] ; comment\r\ncmd = kfm.cmd\r\n",
+            "\ufeff[files] ; comment\r\ncmd = kfm.cmd\r\nmovelist = movelist.dat\r\n",
+        },
+        {
+            "existing key with a comment",
+            "",
+            "[Files]\ncmd = kfm.cmd\nMoveList = old.dat ; mine\nsnd = kfm.snd\n",
+            "[Files]\ncmd = kfm.cmd\nMoveList = movelist.dat ; mine\nsnd = kfm.snd\n",
+        },
+        {
+            "already patched",
+            "",
+            "[Files]\ncmd = kfm.cmd\nmovelist = ./Movelist.dat\n",
+            "[Files]\ncmd = kfm.cmd\nmovelist = ./Movelist.dat\n",
+        },
+        {
+            "no trailing newline",
+            "",
+            "[Files]\ncmd = kfm.cmd",
+            "[Files]\ncmd = kfm.cmd\nmovelist = movelist.dat\n",
+        },
+        {
+            "no command file",
+            "",
+            "[Files]\nst = kfm.cns\n",
+            "[Files]\nst = kfm.cns\n",
+        },
+        {
+            "language key",
+            "ja",
+            "[Files]\ncmd = kfm.cmd\nja.cmd = ja.cmd\nmovelist = movelist.dat\n",
+            "[Files]\ncmd = kfm.cmd\nja.cmd = ja.cmd\nmovelist = movelist.dat\nja.movelist = movelist.dat\n",
+        },
+        {
+            "language section",
+            "ja",
+            "[Files]\ncmd = kfm.cmd\n\n[ja.Files]\ncmd = ja.cmd\n",
+            "[Files]\ncmd = kfm.cmd\n\n[ja.Files]\ncmd = ja.cmd\nmovelist = movelist.dat\n",
+        },
+        {
+            "language section as the last line without a trailing newline",
+            "ja",
+            "[Files]\ncmd = kfm.cmd\nja.cmd = ja.cmd\n[ja.Files]",
+            "[Files]\ncmd = kfm.cmd\nja.cmd = ja.cmd\n[ja.Files]\nmovelist = movelist.dat\n",
+        },
+    }
+
+    for _, test := range tests {
+        t.Run(test.name, func(t *testing.T) {
+            got, changed, err := patch_def_data("kfm.def", []byte(test.def), "movelist.dat", test.lang)
+            if err != nil {
+                t.Fatal(err)
+            }
+
+            if string(got) != test.want || changed != (test.want != test.def) {
+                t.Errorf("got .def (changed: %v):\n%q\nwant:\n%q", changed, got, test.want)
+            }
+        })
+    }
+}
@@ -0,0 +1,179 @@
+package iguana
+
+import (
+    "fmt"
+    "path/filepath"
+    "strings"
+)
+
+// how many unchanged lines are shown around each change in a diff
+const diff_context = 3
+
+// Diff returns a unified diff between the old and new contents of a file, or an empty string if they're the same
+// a missing old file can be given as nil, in which case it's shown as /dev/null
+func Diff(path string, old []byte, new []byte) string {
+    return unified_diff(path, old, new)
+}
+
+// a single line of a diff: ' ' if it's in both files, '-' if it was removed, or '+' if it was added
+type diff_line struct {
+    op   byte
+    text string
+}
+
+func unified_diff(path string, old []byte, new []byte) string {
+    // Works out which lines changed between two versions of a file, then groups them into hunks with some context around them
+
+    if string(old) == string(new) {
+        return ""
+    }
+
+    // lines keep their newlines while they're compared, so that a last line without one counts as changed when one gets added
+    old_lines := split_diff_lines(string(old))
+    new_lines := split_diff_lines(string(new))
+    lines := diff_lines(old_lines, new_lines)
+
+    // paths like ./movelist.dat are tidied up, so that they don't end up as a/./movelist.dat
+    path = filepath.Clean(path)
+
+    old_name := "a/" + path
+    if old == nil {
+        old_name = "/dev/null"
+    }
+
+    output := "--- " + old_name + "\n+++ b/" + path + "\n"
+
+    // line numbers (0-based) in the old and new files at the start of each diff line
+    old_at := make([]int, len(lines) + 1)
+    new_at := make([]int, len(lines) + 1)
+
+    for i, line := range lines {
+        old_at[i+1], new_at[i+1] = old_at[i], new_at[i]
+        if line.op != '+' {old_at[i+1]++}
+        if line.op != '-' {new_at[i+1]++}
+    }
+
+    for start := 0; start < len(lines); {
+        // find the next change, then take in every change close enough to it to share a hunk
+        if lines[start].op == ' ' {
+            start++
+            continue
+        }
+
+        first := start - diff_context
+        if first < 0 {first = 0}
+
+        end := start
+        for i := start; i < len(lines) && i <= end + 2 * diff_context + 1; i++ {
+            if lines[i].op != ' ' {
+                end = i
+            }
+        }
+
+        last := end + diff_context
+        if last >= len(lines) {last = len(lines) - 1}
+
+        output += fmt.Sprintf("@@ -%s +%s @@\n", hunk_range(old_at[first], old_at[last+1] - old_at[first]), hunk_range(new_at[first], new_at[last+1] - new_at[first]))
+
+        for _, line := range lines[first:last+1] {
+            output += string(line.op) + line.text
+
+            if !strings.HasSuffix(line.text, "\n") {
+                output += "\n\\ No newline at end of file\n"
+            }
+        }
+
+        start = last + 1
+    }
+
+    return output
+}
+
+func hunk_range(start int, length int) string {
+    // Formats one side of a hunk header, e.g. "4,7"
+    // an empty side points at the line before it, which is how diff says where lines were added or removed
+
+    if length == 0 {
+        return fmt.Sprintf("%d,0", start)
+    }
+
+    if length == 1 {
+        return fmt.Sprintf("%d", start + 1)
+    }
+
+    return fmt.Sprintf("%d,%d", start + 1, length)
+}
+
+func split_diff_lines(input string) []string {
+    // Splits text into lines for diffing, with each line keeping the newline it ends with (if it has one)
+
+    if input == "" {
+        return nil
+    }
+
+    lines := strings.SplitAfter(input, "\n")
+    if lines[len(lines)-1] == "" {
+        lines = lines[:len(lines)-1]
+    }
+
+    return lines
+}
+
+func split_lines(input string) []string {
+    // Splits text into lines, without an extra empty one for a trailing newline
+
+    if input == "" {
+        return nil
+    }
+
+    return strings.Split(strings.TrimSuffix(input, "\n"), "\n")
+}
+
+func diff_lines(old []string, new []string) []diff_line {
+    // Lines up two lists of lines using their longest common subsequence
+    // movelists and .def files are small, so the simple quadratic approach is plenty
+
+    lengths := make([][]int, len(old) + 1)
+    for i := range lengths {
+        lengths[i] = make([]int, len(new) + 1)
+    }
+
+    for i := len(old) - 1; i >= 0; i-- {
+        for j := len(new) - 1; j >= 0; j-- {
+            if old[i] == new[j] {
+                lengths[i][j] = lengths[i+1][j+1] + 1
+            } else if lengths[i+1][j] >= lengths[i][j+1] {
+                lengths[i][j] = lengths[i+1][j]
+            } else {
+                lengths[i][j] = lengths[i][j+1]
+            }
+        }
+    }
+
+    var output []diff_line
+    i, j := 0, 0
+
+    for i < len(old) && j < len(new) {
+        switch {
+        case old[i] == new[j]:
+            output = append(output, diff_line{' ', old[i]})
+            i++
+            j++
+        case lengths[i+1][j] >= lengths[i][j+1]:
+            output = append(output, diff_line{'-', old[i]})
+            i++
+        default:
+            output = append(output, diff_line{'+', new[j]})
+            j++
+        }
+    }
+
+    for ; i < len(old); i++ {
+        output = append(output, diff_line{'-', old[i]})
+    }
+    for ; j < len(new); j++ {
+        output = append(output, diff_line{'+', new[j]})
+    }
+
+    return output
+}
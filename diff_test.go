@@ -0,0 +1,99 @@
+package iguana
+
+import (
+    "testing"
+)
+
+func TestDiff(t *testing.T) {
+    tests := []struct {
+        name string
+        old  string
+        new  string
+        want string
+    }{
+        {
+            "unchanged",
+            "a\nb\n",
+            "a\nb\n",
+            "",
+        },
+        {
+            "changes far apart get their own hunks",
+            "a\nb\nc\nd\ne\nf\ng\nh\ni\nj\nk\nl\nm\nn\n",
+            "a\nb\nc\nD\ne\nf\ng\nh\ni\nj\nk\nl\nn\nN2\n",
+            "@@ -1,7 +1,7 @@\n a\n b\n c\n-d\n+D\n e\n f\n g\n" +
+                "@@ -10,5 +10,5 @@\n j\n k\n l\n-m\n n\n+N2\n",
+        },
+        {
+            "changes close together share a hunk",
+            "a\nb\nc\nd\ne\nf\ng\nh\n",
+            "a\nB\nc\nd\ne\nf\ng\nH\n",
+            "@@ -1,8 +1,8 @@\n a\n-b\n+B\n c\n d\n e\n f\n g\n-h\n+H\n",
+        },
+        {
+            "added at the start",
+            "a\nb\nc\n",
+            "x\na\nb\nc\n",
+            "@@ -1,3 +1,4 @@\n+x\n a\n b\n c\n",
+        },
+        {
+            "removed from the middle",
+            "a\nb\nc\nd\n",
+            "a\nd\n",
+            "@@ -1,4 +1,2 @@\n a\n-b\n-c\n d\n",
+        },
+        {
+            "newline added at the end",
+            "a\nb",
+            "a\nb\n",
+            "@@ -1,2 +1,2 @@\n a\n-b\n\\ No newline at end of file\n+b\n",
+        },
+        {
+            "newline removed from the end",
+            "a\nb\n",
+            "a\nc",
+            "@@ -1,2 +1,2 @@\n a\n-b\n+c\n\\ No newline at end of file\n",
+        },
+        {
+            "unchanged last line without a newline",
+            "a\nx",
+            "b\nx",
+            "@@ -1,2 +1,2 @@\n-a\n+b\n x\n\\ No newline at end of file\n",
+        },
+        {
+            "everything removed",
+            "a\nb\n",
+            "",
+            "@@ -1,2 +0,0 @@\n-a\n-b\n",
+        },
+    }
+
+    for _, test := range tests {
+        t.Run(test.name, func(t *testing.T) {
+            want := test.want
+            if want != "" {
+                want = "--- a/movelist.dat\n+++ b/movelist.dat\n" + want
+            }
+
+            if got := Diff("movelist.dat", []byte(test.old), []byte(test.new)); got != want {
+                t.Errorf("got diff:\n%s\nwant:\n%s", got, want)
+            }
+        })
+    }
+}
+
+func TestDiffNewFile(t *testing.T) {
+    want := "--- /dev/null\n+++ b/movelist.dat\n@@ -0,0 +1,3 @@\n+a\n+b\n+c\n"
+
+    if got := Diff("movelist.dat", nil, []byte("a\nb\nc\n")); got != want {
+        t.Errorf("got diff:\n%s\nwant:\n%s", got, want)
+    }
+}
+
+func TestDiffPath(t *testing.T) {
+    want := "--- a/chars/kfm/movelist.dat\n+++ b/chars/kfm/movelist.dat\n@@ -1 +1 @@\n-a\n+b\n"
+
+    if got := Diff("./chars/kfm/../kfm/movelist.dat", []byte("a\n"), []byte("b\n")); got != want {
+        t.Errorf("got diff:\n%s\nwant:\n%s", got, want)
+    }
+}
@@ -0,0 +1,127 @@
+package iguana
+
+import (
+    "errors"
+    "fmt"
+    "os"
+    "strings"
+    "gopkg.in/ini.v1"
+)
+
+// NotFoundError is returned when a file Iguana was asked to read doesn't exist
+type NotFoundError struct {
+    Path string
+    Err  error
+}
+
+func (e *NotFoundError) Error() string {
+    return "file not found: " + e.Path
+}
+
+func (e *NotFoundError) Unwrap() error {
+    return e.Err
+}
+
+// ReadError is returned when a file exists but couldn't be read (e.g. it's a folder, or permissions are missing)
+type ReadError struct {
+    Path string
+    Err  error
+}
+
+func (e *ReadError) Error() string {
+    return "couldn't read " + e.Path + ": " + e.Err.Error()
+}
+
+func (e *ReadError) Unwrap() error {
+    return e.Err
+}
+
+// ParseError is returned when a .def or translation file is too malformed to be parsed
+// command and state files never cause one, as they're read the way MUGEN reads them, skipping anything it would skip
+// Line is the 1-based line number the problem was found on, or 0 if it couldn't be determined
+type ParseError struct {
+    Path string
+    Line int
+    Err  error
+}
+
+func (e *ParseError) Error() string {
+    if e.Line > 0 {
+        return fmt.Sprintf("couldn't parse %s (line %d): %s", e.Path, e.Line, strings.TrimSpace(e.Err.Error()))
+    }
+
+    return fmt.Sprintf("couldn't parse %s: %s", e.Path, strings.TrimSpace(e.Err.Error()))
+}
+
+func (e *ParseError) Unwrap() error {
+    return e.Err
+}
+
+// MissingCmdError is returned when a .def file has no cmd key in its [Files] section
+type MissingCmdError struct {
+    Def string
+}
+
+func (e *MissingCmdError) Error() string {
+    return "no command file found in " + e.Def
+}
+
+func read_file(path string) ([]byte, error) {
+    // Reads a file from disk, wrapping any failures into one of the error types above
+
+    file_data, err := os.ReadFile(path)
+
+    if errors.Is(err, os.ErrNotExist) {
+        return nil, &NotFoundError{Path: path, Err: err}
+    }
+
+    if err != nil {
+        return nil, &ReadError{Path: path, Err: err}
+    }
+
+    return file_data, nil
+}
+
+func load_ini(path string, file_data []byte, options ini.LoadOptions) (*ini.File, error) {
+    // Parses INI data, wrapping any failures into a ParseError
+
+    parsed_ini, err := ini.LoadSources(options, file_data)
+    if err != nil {
+        return nil, &ParseError{Path: path, Line: find_error_line(file_data, err), Err: err}
+    }
+
+    return parsed_ini, nil
+}
+
+func find_error_line(file_data []byte, err error) int {
+    // Tries to figure out which line an INI parsing error came from
+    // the INI library only gives us the offending line's text, so we have to go looking for it ourselves
+
+    var bad_line string
+
+    switch e := err.(type) {
+    case ini.ErrDelimiterNotFound:
+        bad_line = e.Line
+    case ini.ErrEmptyKeyName:
+        bad_line = e.Line
+    default:
+        // the rest of the library's errors are formatted as "description: line"
+        msg := err.Error()
+        if i := strings.LastIndex(msg, ": "); i != -1 {
+            bad_line = msg[i+2:]
+        }
+    }
+
+    bad_line = strings.TrimSpace(bad_line)
+    if bad_line == "" {
+        return 0
+    }
+
+    for i, line := range strings.Split(string(file_data), "\n") {
+        if strings.TrimSpace(line) == bad_line {
+            return i + 1
+        }
+    }
+
+    return 0
+}
@@ -1,5 +1,5 @@
 module github.com/superfromnd/iguana
 
-go 1.13
+go 1.16
 
 require gopkg.in/ini.v1 v1.67.0 // indirect
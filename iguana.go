@@ -0,0 +1,300 @@
+// Package iguana generates movelist.dat files for Ikemen GO and M.U.G.E.N characters
+// using only their command definitions (.cmd) file.
+package iguana
+
+import (
+    "fmt"
+    "io"
+    "os"
+)
+
+// Options controls how a command file gets converted into a movelist
+type Options struct {
+    Debug         bool              // enables debug logging
+    KeepOneButton bool              // preserve one-button, non-hyper moves
+    KeepAI        bool              // preserve move commands detected as AI-only
+    UseKP         bool              // use LP/MP/HP/LK/MK/HK instead of A/B/C/X/Y/Z
+    NoMotions     bool              // don't compress directions to motion inputs
+    HeaderColor   string            // hex-color (without #) to use for headers
+    PowerColor    string            // hex-color (without #) to use for move power usage
+    ChargeFormat  string            // how charge inputs are annotated; {dir} is the charged direction or button, {time} the ticks it's held for; defaults to "[{dir}] charge, " in the movelist's language
+    ReleaseFormat string            // how released buttons are annotated; {button} is the released button; defaults to "{button}(release)" in the movelist's language
+    SplitVariants bool              // list alternative inputs for a move on separate lines instead of joining them with "or"
+    StateTypes    int               // how air-only and ground-only moves are marked, one of the StateTypes constants
+    PowerStyle    int               // how a move's power usage is shown, one of the PowerStyle constants
+    PowerPerBar   int               // how much power makes up one bar (or level), for PowerStyleLevels and PowerStyleBars
+    BarGlyph      string            // the text repeated for each bar with PowerStyleBars
+    Language      string            // language of the headers and labels Iguana adds, one of Languages(); defaults to the character's, then English
+    Translation   map[string]string // the user's own text for headers and labels, which takes priority over Language (see LoadTranslation)
+    Log           io.Writer         // where debug logging gets written to, defaults to stdout
+}
+
+// DefaultOptions returns the same options the Iguana CLI uses when given no arguments
+func DefaultOptions() Options {
+    return Options{
+        HeaderColor:   "f0f000",
+        PowerColor:    "bebebe",
+        PowerPerBar:   1000,
+        BarGlyph:      "*",
+    }
+}
+
+// ways of marking moves that can only be done in the air or on the ground, used by Options.StateTypes
+const (
+    StateTypesOff   = iota // don't mark them at all
+    StateTypesLabel        // add "(Air)" or "(Ground)" after the move's name
+    StateTypesGroup        // list air-only moves under their own header
+)
+
+// ways of showing how much power a move uses, used by Options.PowerStyle
+// levels and bars never go past the character's max power (from the [Data] section of its constants file), if it's known
+const (
+    PowerStyleNumbers = iota // the raw amount, e.g. "(2000)"
+    PowerStyleLevels         // the number of bars as a level, e.g. "(Lv2)"
+    PowerStyleBars           // BarGlyph repeated for each bar, e.g. "(**)"
+)
+
+var hr = "========================================================"
+
+// Source is a place in a character's files that part of a movelist came from
+type Source struct {
+    File    string `json:"file"`
+    Line    int    `json:"line"`    // 1-based line number of the section (or ZSS controller)
+    Section string `json:"section"` // e.g. "State -1, Fireball" or "Command"
+}
+
+func (s Source) String() string {
+    return fmt.Sprintf("%s:%d [%s]", s.File, s.Line, s.Section)
+}
+
+// LineSource maps a single line of a generated movelist back to the sections it was made from
+type LineSource struct {
+    Line     int      `json:"line"`     // 1-based line number in the movelist
+    Text     string   `json:"text"`
+    Move     Source   `json:"move"`     // the controller that changes into the move
+    Commands []Source `json:"commands"` // every [Command] the move's inputs came from
+}
+
+// stores data from [Command] sections
+type Command struct {
+    name    string
+    command string
+    time    int
+    source  Source
+}
+
+// stores data from [State -1] sections
+type Move struct {
+    name       string
+    triggerall []*expr          // conditions that have to be true for every trigger group
+    groups     []trigger_group  // numbered trigger groups (trigger1, trigger2...), any one of which activates the move
+    commands   [][]string       // every combination of commands that activates this move
+    statetypes int              // bitmask of the statetypes the move can be done in (see statetype_values)
+    ctrl       int              // bitmask of the ctrl values the move can be done with (see ctrl_values)
+    value      string           // the state the move changes to
+    power      int              // the least amount of power the move's triggers need
+    source     Source
+}
+
+// the lines of a single numbered trigger group, all of which have to be true at once
+type trigger_group struct {
+    number int
+    lines  []*expr
+}
+
+// combined data from the above two, used when creating movelist data file
+type MoveEntry struct {
+    name       string
+    variants   []Variant // every way of inputting the move
+    power      int
+    statetypes int
+    state      string // the state the move changes to
+    depth      int    // how many moves deep this is as a follow-up; follow-ups come right after the move they follow up
+    source     Source
+    commands   []Source // the [Command] sections its variants were made from
+    header     string   // the header it was listed under, if it was read from an existing movelist
+    verbatim   bool     // read from an existing movelist, so it's written back out without filtering any of it
+}
+
+// a single way of inputting a move, made from one alternative of its triggers
+type Variant struct {
+    command string
+    held    string
+}
+
+// Name returns the name the move is listed under, without its power usage or statetype label
+func (mv MoveEntry) Name() string {
+    return mv.name
+}
+
+// Variants returns every way of inputting the move, in the order they're listed
+func (mv MoveEntry) Variants() []Variant {
+    return mv.variants
+}
+
+// Power returns how much power the move uses, or 0 if it doesn't use any
+func (mv MoveEntry) Power() int {
+    return mv.power
+}
+
+// Header returns the header the move was listed under, if it was read from an existing movelist
+func (mv MoveEntry) Header() string {
+    return mv.header
+}
+
+// Depth returns how many moves deep this is as a follow-up, with 0 for a move that isn't one
+func (mv MoveEntry) Depth() int {
+    return mv.depth
+}
+
+// Input returns the variant the way it's written in a movelist made with the given options, e.g. "_QCF^A"
+func (v Variant) Input(opt Options) string {
+    cv := new_converter(opt)
+    return cv.detokenize(v.held) + cv.detokenize(v.command)
+}
+
+// holds the state of a single conversion, so that none of it has to live in package globals
+type converter struct {
+    opt   Options
+    known map[string]float64 // triggers whose values are known ahead of time, like the character's max power
+    text  map[string]string  // the headers and labels to use, after translation
+}
+
+func new_converter(opt Options) *converter {
+    if opt.Log == nil {
+        opt.Log = os.Stdout
+    }
+
+    if opt.PowerPerBar <= 0 {
+        opt.PowerPerBar = DefaultOptions().PowerPerBar
+    }
+
+    if opt.BarGlyph == "" {
+        opt.BarGlyph = DefaultOptions().BarGlyph
+    }
+
+    cv := &converter{opt: opt, known: make(map[string]float64)}
+    cv.load_translation()
+
+    // the annotations for charges and releases are in the movelist's language, unless they've been given
+    if cv.opt.ChargeFormat == "" {
+        cv.opt.ChargeFormat = "[{dir}] " + cv.translate("charge") + ", "
+    }
+
+    if cv.opt.ReleaseFormat == "" {
+        cv.opt.ReleaseFormat = "{button}(" + cv.translate("release") + ")"
+    }
+
+    return cv
+}
+
+func (cv *converter) debug(a ...interface{}) {
+    // Prints the given values, but only when debug logging is enabled
+
+    if cv.opt.Debug {
+        fmt.Fprintln(cv.opt.Log, a...)
+    }
+}
+
+// Character holds the paths to the files a character's movelist is made from
+type Character struct {
+    Def       string   // the character's definitions file, if there is one
+    Cmd       string   // the command file, where commands and [Statedef -1] are found
+    States    []string // state files, which are followed to find out how much power a move costs
+    Constants string   // the constants file, where the character's max power is found
+    Common    []string // Ikemen GO's shared command files, whose commands can be used by any character
+    Language  string   // the language these files were picked for, if the .def gives files for several (e.g. "ja")
+    Movelist  string   // the movelist the .def points to, if it has one
+}
+
+// Convert takes a path to a command file and returns its movelist.dat as a string
+// failures are reported as a NotFoundError or ReadError
+func Convert(path string, opt Options) (string, error) {
+    return ConvertCharacter(Character{Cmd: path}, opt)
+}
+
+// ConvertDef takes a path to a .def file and returns the movelist.dat of its command file as a string
+// failures are reported as a NotFoundError, ReadError, ParseError (for the .def itself) or MissingCmdError
+func ConvertDef(def string, opt Options) (string, error) {
+    char, err := load_def(def, "")
+    if err != nil {
+        return "", err
+    }
+
+    return ConvertCharacter(char, opt)
+}
+
+// ConvertCharacter takes the files of a character and returns its movelist.dat as a string
+func ConvertCharacter(char Character, opt Options) (string, error) {
+    movelist, _, err := ConvertCharacterWithSources(char, opt)
+    return movelist, err
+}
+
+// ConvertCharacterWithSources is like ConvertCharacter, but also returns where each line of the movelist came from
+// headers and blank lines don't come from anywhere, so they're left out
+func ConvertCharacterWithSources(char Character, opt Options) (string, []LineSource, error) {
+    // characters loaded for a language get a movelist in that language, unless another was asked for
+    if opt.Language == "" {
+        opt.Language = char.Language
+    }
+
+    cv := new_converter(opt)
+
+    commands, moves, state_costs, err := cv.load_character(char)
+    if err != nil {
+        return "", nil, err
+    }
+
+    // combine the parsed data into a list of move names and command inputs
+    move_table := cv.assemble_move_table(commands, moves, state_costs)
+
+    // format the movelist we just made into the movelist.dat format
+    // (see https://github.com/ikemen-engine/Ikemen-GO/wiki/Miscellaneous-Info#movelists)
+    movelist, sources := cv.format_move_table(move_table)
+
+    for _, line := range sources {
+        cv.debug("Line", line.Line, "comes from", line.Move, "with commands", line.Commands)
+    }
+
+    return movelist, sources, nil
+}
+
+func (cv *converter) load_character(char Character) ([]Command, []Move, map[int]int, error) {
+    // Reads every file of a character, returning its commands, its moves, and how much power the states of its moves cost
+
+    path := char.Cmd
+
+    // loads the file, then parses it
+    cv.debug("Reading input file...")
+    file_data, err := read_file(path)
+    if err != nil {
+        return nil, nil, nil, err
+    }
+
+    cv.debug("Parsing as INI data...")
+    parsed_ini := parse_mugen_ini(path, file_data)
+
+    // the character's max power is needed to work out the cost of moves that check for it
+    cv.scrape_constants(char.Constants)
+
+    // parse sections into dedicated structs
+    remap := cv.scrape_remap(parsed_ini)
+    commands := cv.scrape_commands(parsed_ini)
+    commands = append(commands, cv.scrape_common_commands(char.Common, commands)...)
+    moves := cv.scrape_moves(parsed_ini)
+
+    // state files can have a [Statedef -1] of their own, which gets merged with the command file's
+    state_files := cv.load_state_files(char.States, char.Cmd)
+    moves = append(moves, cv.scrape_state_moves(state_files)...)
+    state_costs := cv.scrape_state_costs(append([]*ini_document{parsed_ini}, state_files...))
+
+    // Ikemen GO characters can also write their states in ZSS, which needs a reader of its own
+    moves = append(moves, cv.scrape_zss_files(char.States, char.Cmd, state_costs)...)
+
+    // swap out any remapped buttons so the movelist shows what the player actually has to press
+    for c := range commands {
+        commands[c].command = apply_remap(commands[c].command, remap)
+    }
+
+    return commands, moves, state_costs, nil
+}
@@ -0,0 +1,57 @@
+package iguana
+
+import (
+    "path/filepath"
+    "strings"
+    "testing"
+)
+
+func TestConvertCharacterWithSources(t *testing.T) {
+    tests := []struct {
+        name    string
+        options func(opt *Options)
+    }{
+        {"default", func(opt *Options) {}},
+        {"split variants", func(opt *Options) {opt.SplitVariants = true}},
+    }
+
+    for _, test := range tests {
+        t.Run(test.name, func(t *testing.T) {
+            opt := DefaultOptions()
+            test.options(&opt)
+
+            movelist, sources, err := ConvertCharacterWithSources(Character{Cmd: filepath.Join("res", "test.cmd")}, opt)
+            if err != nil {
+                t.Fatal(err)
+            }
+
+            // every line with inputs on it is a move, so it should have come from somewhere
+            lines := strings.Split(movelist, "\n")
+            moves := 0
+            for _, line := range lines {
+                if strings.Contains(line, "\t\t\t") {moves++}
+            }
+
+            if len(sources) != moves {
+                t.Errorf("got %d line sources, want one for each of the %d moves", len(sources), moves)
+            }
+
+            previous := 0
+            for _, source := range sources {
+                if source.Line <= previous || source.Line > len(lines) {
+                    t.Errorf("line source %d (%q) is out of order or past the end of the movelist", source.Line, source.Text)
+                    continue
+                }
+                previous = source.Line
+
+                if got := lines[source.Line-1]; got != source.Text {
+                    t.Errorf("line source %d has text %q, but the movelist has %q there", source.Line, source.Text, got)
+                }
+
+                if source.Move.Line == 0 || len(source.Commands) == 0 {
+                    t.Errorf("line source %d (%q) doesn't point at its move and commands: %+v", source.Line, source.Text, source)
+                }
+            }
+        })
+    }
+}
@@ -0,0 +1,225 @@
+package iguana
+
+import (
+    "errors"
+    "fmt"
+    "os"
+    "path/filepath"
+    "regexp"
+    "sort"
+    "strings"
+)
+
+// matches every glyph in some text, known or not, e.g. "_QCF", "^A", "~DF" or "_+"
+var any_glyph_regex = regexp.MustCompile(`[_^~](\+|[A-Z]+)`)
+
+// matches a valid color tag, e.g. "<#f0f000>" or "<#fff>"
+var valid_color_regex = regexp.MustCompile(`^<#([0-9A-Fa-f]{3}|[0-9A-Fa-f]{6})>$`)
+
+// Problem is something wrong with a movelist (or the .def pointing to it), found by LintMovelist or LintDef
+type Problem struct {
+    File    string `json:"file"`
+    Line    int    `json:"line"` // 1-based, or 0 if it's about the whole file
+    Message string `json:"message"`
+}
+
+func (p Problem) String() string {
+    if p.Line == 0 {
+        return fmt.Sprintf("%s: %s", p.File, p.Message)
+    }
+    return fmt.Sprintf("%s:%d: %s", p.File, p.Line, p.Message)
+}
+
+// LintMovelist checks a movelist.dat for glyphs Iguana doesn't know, broken color tags,
+// and (if the character's files are given, with Cmd set) moves that the character doesn't have anymore
+func LintMovelist(path string, char Character, opt Options) ([]Problem, error) {
+    return new_converter(opt).lint_movelist(path, char)
+}
+
+// LintDef checks every movelist the given .def points to, in every language it has files for,
+// along with the movelist keys themselves pointing to files that don't exist
+// movelists already in checked are skipped, and the ones that get checked are added to it, so that a roster whose .defs share
+// a movelist only checks it once; it's keyed by each movelist's absolute path (see filepath.Abs), and can be nil to check everything
+func LintDef(def string, checked map[string]bool, opt Options) ([]Problem, error) {
+    if checked == nil {
+        checked = make(map[string]bool)
+    }
+
+    return new_converter(opt).lint_def(def, checked)
+}
+
+func (cv *converter) lint_def(def string, checked map[string]bool) ([]Problem, error) {
+    // Uses the same [Files] resolution as conversion, so each language's movelist is checked against that language's command file
+
+    parsed_ini, err := read_def(def)
+    if err != nil {
+        return nil, err
+    }
+
+    var problems []Problem
+    var seen []string
+
+    for _, lang := range append([]string{""}, def_languages(parsed_ini)...) {
+        // a character without a command file can still have its movelist checked, just not against its moves
+        char, err := load_def(def, lang)
+        var missing_cmd *MissingCmdError
+        if err != nil && !errors.As(err, &missing_cmd) {
+            return problems, err
+        }
+
+        // languages without a movelist of their own fall back on the regular one, which only needs checking once
+        if char.Movelist == "" || contains_string(seen, char.Movelist) {continue}
+        seen = append(seen, char.Movelist)
+
+        if info, err := os.Stat(char.Movelist); err != nil || info.IsDir() {
+            problems = append(problems, Problem{File: def, Line: def_key_line(def, char.Movelist), Message: "movelist points to a missing file, " + char.Movelist})
+            continue
+        }
+
+        // other .defs can use the same movelist too (e.g. palette or AI variants of a character)
+        if path, err := filepath.Abs(char.Movelist); err == nil {
+            if checked[path] {continue}
+            checked[path] = true
+        }
+
+        found, err := cv.lint_movelist(char.Movelist, char)
+        if err != nil {
+            return problems, err
+        }
+        problems = append(problems, found...)
+    }
+
+    return problems, nil
+}
+
+func def_key_line(def string, path string) int {
+    // Finds the line of the movelist key (of any language) in a .def that points to the given path, or 0 if there isn't one
+
+    file_data, err := read_file(def)
+    if err != nil {
+        return 0
+    }
+
+    for _, section := range parse_mugen_ini(def, file_data).sections {
+        if _, name := split_language(section.name); !strings.EqualFold(name, "Files") {continue}
+
+        for _, key := range section.keys {
+            _, name := split_language(key.name)
+            if strings.EqualFold(name, "movelist") && same_path(filepath.Join(filepath.Dir(def), key.value), path) {
+                return key.line
+            }
+        }
+    }
+
+    return 0
+}
+
+func (cv *converter) lint_movelist(path string, char Character) ([]Problem, error) {
+    // Checks each line's color tags and glyphs as written, then reads the movelist to compare its moves with the character's
+
+    file_data, err := read_file(path)
+    if err != nil {
+        return nil, err
+    }
+
+    var problems []Problem
+    add := func(line int, format string, a ...interface{}) {
+        problems = append(problems, Problem{File: path, Line: line, Message: fmt.Sprintf(format, a...)})
+    }
+
+    text := strings.TrimPrefix(string(file_data), "\uFEFF")
+    tokens := glyph_tokens()
+
+    for i, line := range split_lines(text) {
+        line = strings.TrimSuffix(line, "\r")
+
+        for _, message := range lint_color_tags(line) {
+            add(i + 1, "%s", message)
+        }
+
+        // glyphs are only looked for in a move's inputs, as its name is just text
+        tab := strings.Index(line, "\t")
+        if tab == -1 {continue}
+
+        inputs := color_tag_regex.ReplaceAllString(line[tab:], "")
+        for _, glyph := range any_glyph_regex.FindAllString(inputs, -1) {
+            if _, ok := tokens[glyph]; !ok {
+                add(i + 1, "unknown glyph %s", glyph)
+            }
+        }
+    }
+
+    // only moves that are still in the character's files are expected to show up in its movelist
+    if char.Cmd == "" {
+        return problems, nil
+    }
+
+    _, moves, _, err := cv.load_character(char)
+    if err != nil {
+        return problems, err
+    }
+
+    names := make(map[string]bool)
+    for _, move := range moves {
+        names[movelist_entry_name(move.name)] = true
+    }
+
+    for _, entry := range cv.parse_movelist(path, text) {
+        // lines without inputs are notes, not moves
+        if len(entry.variants) == 0 {continue}
+
+        if !names[movelist_entry_name(entry.name)] {
+            add(entry.source.Line, "%s isn't a move in %s anymore", entry.name, filepath.Base(char.Cmd))
+        }
+    }
+
+    sort.SliceStable(problems, func(a, b int) bool {return problems[a].Line < problems[b].Line})
+
+    return problems, nil
+}
+
+func lint_color_tags(line string) []string {
+    // Checks that every color tag in a line is well-formed, and that each one is closed with a </> (and every </> closes one)
+
+    var messages []string
+    open := 0
+
+    for pos := strings.Index(line, "<"); pos != -1; {
+        end := strings.IndexAny(line[pos+1:], "<>")
+        if end == -1 {
+            messages = append(messages, "unfinished tag " + strings.TrimSpace(line[pos:]))
+        } else if line[pos+1+end] == '<' {
+            messages = append(messages, "unfinished tag " + strings.TrimSpace(line[pos:pos+1+end]))
+        } else {
+            tag := line[pos : pos+end+2]
+
+            switch {
+            case tag == "</>":
+                if open == 0 {
+                    messages = append(messages, "</> without a color tag to close")
+                } else {
+                    open--
+                }
+            case valid_color_regex.MatchString(tag):
+                open++
+            default:
+                messages = append(messages, "malformed color tag " + tag)
+
+                // it was still meant to start a color, so its </> isn't reported as well
+                if strings.HasPrefix(tag, "<#") {
+                    open++
+                }
+            }
+        }
+
+        next := strings.Index(line[pos+1:], "<")
+        if next == -1 {break}
+        pos += next + 1
+    }
+
+    if open > 0 {
+        messages = append(messages, "color tag isn't closed with </>")
+    }
+
+    return messages
+}
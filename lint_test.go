@@ -0,0 +1,127 @@
+package iguana
+
+import (
+    "fmt"
+    "os"
+    "path/filepath"
+    "testing"
+)
+
+func TestLintColorTags(t *testing.T) {
+    tests := []struct {
+        line string
+        want []string
+    }{
+        {"Fireball <#bebebe>(1000)</>\t\t\t_QCF^A", nil},
+        {"<#fff>Short</>", nil},
+        {"Fireball <#bebebe>(1000)", []string{"color tag isn't closed with </>"}},
+        {"Fireball</>", []string{"</> without a color tag to close"}},
+        {"<#bebebg>(1000)</>", []string{"malformed color tag <#bebebg>"}},
+        {"<#bebebe(1000)</>", []string{"unfinished tag <#bebebe(1000)", "</> without a color tag to close"}},
+        {"Fireball <#bebebe", []string{"unfinished tag <#bebebe"}},
+    }
+
+    for _, test := range tests {
+        if got := lint_color_tags(test.line); fmt.Sprint(got) != fmt.Sprint(test.want) {
+            t.Errorf("lint_color_tags(%q) = %q, want %q", test.line, got, test.want)
+        }
+    }
+}
+
+func TestLintMovelist(t *testing.T) {
+    tests := []struct {
+        name     string
+        movelist string
+        cmd      string
+        want     []string
+    }{
+        {"fine", "<#f0f000>:Special Moves:</>\nDragon Punch\t\t\t_DSF^X\n", "test.cmd", nil},
+        {"unknown glyphs", "<#f0f000>:Special Moves:</>\nTaunt\t\t\t^TAUNT or _QCF^A\n", "", []string{"2: unknown glyph ^TAUNT"}},
+        {"glyphs in a name", "<#f0f000>:Special Moves:</>\n^TAUNT\t\t\t^A\n", "", nil},
+        {"color tags", "<#f0f000>:Special Moves:\nFireball <#bebebe>(1000)\t\t\t_QCF^A</>\n", "", []string{"1: color tag isn't closed with </>"}},
+        {"moves gone from the command file", "<#f0f000>:Special Moves:</>\nDragon Punch\t\t\t_DSF^X\nOld Move\t\t\t_QCF^A\nA note\n", "test.cmd", []string{"3: Old Move isn't a move in test.cmd anymore"}},
+        {"moves not checked without a command file", "<#f0f000>:Special Moves:</>\nOld Move\t\t\t_QCF^A\n", "", nil},
+    }
+
+    for _, test := range tests {
+        t.Run(test.name, func(t *testing.T) {
+            path := filepath.Join(t.TempDir(), "movelist.dat")
+            if err := os.WriteFile(path, []byte(test.movelist), 0644); err != nil {
+                t.Fatal(err)
+            }
+
+            var char Character
+            if test.cmd != "" {
+                char.Cmd = filepath.Join("res", test.cmd)
+            }
+
+            problems, err := LintMovelist(path, char, DefaultOptions())
+            if err != nil {
+                t.Fatal(err)
+            }
+
+            var got []string
+            for _, problem := range problems {
+                got = append(got, fmt.Sprintf("%d: %s", problem.Line, problem.Message))
+            }
+
+            if fmt.Sprint(got) != fmt.Sprint(test.want) {
+                t.Errorf("got problems %q, want %q", got, test.want)
+            }
+        })
+    }
+}
+
+func TestLintDef(t *testing.T) {
+    dir := t.TempDir()
+
+    cmd, err := os.ReadFile(filepath.Join("res", "test.cmd"))
+    if err != nil {
+        t.Fatal(err)
+    }
+
+    files := map[string]string{
+        "kfm.cmd":      string(cmd),
+        "movelist.dat": "<#f0f000>:Special Moves:</>\nDragon Punch\t\t\t_DSF^X or ^TAUNT\n",
+        "kfm.def":      "[Files]\ncmd = kfm.cmd\nmovelist = movelist.dat\n",
+        "kfm-ai.def":   "[Files]\ncmd = kfm.cmd\nmovelist = movelist.dat\n",
+        "missing.def":  "[Files]\ncmd = kfm.cmd\n; not written yet\nmovelist = missing.dat\n",
+    }
+
+    for name, data := range files {
+        if err := os.WriteFile(filepath.Join(dir, name), []byte(data), 0644); err != nil {
+            t.Fatal(err)
+        }
+    }
+
+    lint := func(def string, checked map[string]bool) []string {
+        problems, err := LintDef(filepath.Join(dir, def), checked, DefaultOptions())
+        if err != nil {
+            t.Fatal(err)
+        }
+
+        var got []string
+        for _, problem := range problems {
+            got = append(got, fmt.Sprintf("%s:%d", filepath.Base(problem.File), problem.Line))
+        }
+        return got
+    }
+
+    if got := lint("missing.def", nil); fmt.Sprint(got) != "[missing.def:4]" {
+        t.Errorf("a movelist key pointing to a missing file gave problems %q, want one on line 4 of the .def", got)
+    }
+
+    // a movelist shared by two .defs is only checked for the first one
+    checked := make(map[string]bool)
+    if got := lint("kfm.def", checked); fmt.Sprint(got) != "[movelist.dat:2]" {
+        t.Errorf("got problems %q, want the unknown glyph on line 2 of the movelist", got)
+    }
+    if got := lint("kfm-ai.def", checked); len(got) != 0 {
+        t.Errorf("checking a movelist a second time gave problems %q, want none", got)
+    }
+
+    // (unless nothing's been checked yet)
+    if got := lint("kfm-ai.def", nil); fmt.Sprint(got) != "[movelist.dat:2]" {
+        t.Errorf("got problems %q, want the unknown glyph on line 2 of the movelist", got)
+    }
+}
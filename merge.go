@@ -0,0 +1,243 @@
+package iguana
+
+import (
+    "regexp"
+    "strings"
+)
+
+// marks the lines of a merged movelist that Iguana added, so that they can be swapped out for new ones the next time it's merged
+// an empty color tag doesn't show up in-game, so this can't change how the movelist looks
+// (taking the tag off of a line turns it into one of the author's own, which Iguana won't touch from then on)
+const generated_tag = "<#000000></>"
+
+// matches color tags, along with whatever they're coloring, e.g. "<#bebebe>(1000)</>"
+var color_span_regex = regexp.MustCompile(`<#[0-9A-Fa-f]*>[^<]*</>`)
+
+// matches any color tag by itself, opening or closing
+var color_tag_regex = regexp.MustCompile(`</?#?[0-9A-Fa-f]*>`)
+
+// MergeMovelist combines a newly generated movelist with an existing one, keeping every entry the author wrote themselves
+// only moves the existing movelist doesn't already have (going by name) are added, each under the header they were generated under
+// lines are tagged as they're added, so that merging again replaces them instead of adding them twice
+//
+// the returned slice gives the line number (1-based) each line of the generated movelist ended up on, or 0 if it was left out
+func MergeMovelist(existing string, generated string) (string, []int) {
+    return merge_movelist(existing, generated)
+}
+
+// a header and the lines under it, used while merging
+type merge_section struct {
+    header string       // the header line itself, or an empty string for lines that come before any header
+    lines  []merge_line // includes any blank lines at the end, which separate it from the next section
+    added  bool         // whether the section itself was added by Iguana
+}
+
+// a single line of a movelist being merged
+type merge_line struct {
+    text      string
+    generated string // the name of the move, if Iguana added this line the last time it was merged
+}
+
+// a line of the generated movelist that's going into the merged one
+type merge_addition struct {
+    index int // where it was in the generated movelist (0-based)
+    line  string
+}
+
+func merge_movelist(existing string, generated string) (string, []int) {
+    // keep the existing file's line endings for everything that gets added to it
+    newline := "\n"
+    if strings.Contains(existing, "\r\n") {
+        newline = "\r\n"
+    }
+
+    // split the existing movelist into its sections
+    // lines added the last time it was merged are kept as placeholders, so that their new versions end up in the same spot
+    var sections []*merge_section
+    authored := make(map[string]bool)
+    current := &merge_section{}
+    sections = append(sections, current)
+
+    for _, line := range split_lines(existing) {
+        line = strings.TrimSuffix(line, "\r")
+        is_generated := strings.Contains(line, generated_tag)
+
+        if is_movelist_header(line) {
+            current = &merge_section{header: strings.ReplaceAll(line, generated_tag, ""), added: is_generated}
+            sections = append(sections, current)
+            continue
+        }
+
+        name := movelist_entry_name(line)
+        switch {
+        case is_generated && name == "":
+            // the blank line before an added section, which gets put back if the section is still needed
+            continue
+        case is_generated:
+            current.lines = append(current.lines, merge_line{generated: name})
+            continue
+        case name != "":
+            authored[name] = true
+        }
+
+        current.lines = append(current.lines, merge_line{text: line})
+    }
+
+    // the generated movelist's lines, either replacing their placeholders or going at the end of the section for their header
+    var header string
+    generated_lines := split_lines(generated)
+    placed := make([]int, len(generated_lines))
+    replacements := make(map[*merge_line]merge_addition)
+    additions := make(map[*merge_section][]merge_addition)
+
+    for i, line := range generated_lines {
+        if is_movelist_header(line) {
+            header = line
+            continue
+        }
+
+        name := movelist_entry_name(line)
+        if name == "" || authored[name] {continue}
+
+        if placeholder := find_placeholder(sections, name, replacements); placeholder != nil {
+            replacements[placeholder] = merge_addition{i, line}
+            continue
+        }
+
+        section := find_merge_section(sections, header)
+        if section == nil {
+            section = &merge_section{header: header, added: true}
+            sections = append(sections, section)
+        }
+
+        additions[section] = append(additions[section], merge_addition{i, line})
+    }
+
+    // put everything back together, with the new lines at the end of their sections (before any blank lines)
+    var output []string
+
+    for s, section := range sections {
+        var lines []string
+        var indices []int
+
+        for l := range section.lines {
+            line := &section.lines[l]
+            if line.generated == "" {
+                lines = append(lines, line.text)
+                indices = append(indices, -1)
+            } else if r, ok := replacements[line]; ok {
+                lines = append(lines, r.line + generated_tag)
+                indices = append(indices, r.index)
+            }
+        }
+
+        // blank lines at the end of a section stay after anything that's added to it
+        trailing := 0
+        for trailing < len(lines) && strings.TrimSpace(lines[len(lines) - 1 - trailing]) == "" {
+            trailing++
+        }
+
+        for _, a := range additions[section] {
+            lines = append(lines[:len(lines) - trailing], append([]string{a.line + generated_tag}, lines[len(lines) - trailing:]...)...)
+            indices = append(indices[:len(indices) - trailing], append([]int{a.index}, indices[len(indices) - trailing:]...)...)
+        }
+
+        // the last section doesn't need anything separating it from the next one
+        if s == len(sections) - 1 {
+            lines = lines[:len(lines) - trailing]
+        }
+
+        // sections added by Iguana are left out once nothing's left in them
+        if section.added {
+            if strings.TrimSpace(strings.Join(lines, "")) == "" {continue}
+
+            if len(output) > 0 {
+                output = append(output, generated_tag)
+            }
+            output = append(output, section.header + generated_tag)
+        } else if section.header != "" {
+            output = append(output, section.header)
+        }
+
+        for l, line := range lines {
+            output = append(output, line)
+            if indices[l] != -1 {
+                placed[indices[l]] = len(output)
+            }
+        }
+    }
+
+    if len(output) == 0 {
+        return "", placed
+    }
+
+    return strings.Join(output, newline) + newline, placed
+}
+
+func find_placeholder(sections []*merge_section, name string, taken map[*merge_line]merge_addition) *merge_line {
+    // Finds the first line added for the given move the last time the movelist was merged, that hasn't been used yet
+
+    for _, section := range sections {
+        for l := range section.lines {
+            line := &section.lines[l]
+            if _, ok := taken[line]; !ok && line.generated == name {
+                return line
+            }
+        }
+    }
+
+    return nil
+}
+
+func find_merge_section(sections []*merge_section, header string) *merge_section {
+    // Finds the section with the same header as the given one, ignoring its color and spacing
+
+    for _, section := range sections {
+        if section.header != "" && movelist_header_name(section.header) == movelist_header_name(header) {
+            return section
+        }
+    }
+
+    return nil
+}
+
+func is_movelist_header(line string) bool {
+    // Checks if a movelist line is a header, e.g. "<#f0f000>:Special Moves:</>"
+
+    name := strings.TrimSpace(color_tag_regex.ReplaceAllString(line, ""))
+    return len(name) > 1 && strings.HasPrefix(name, ":") && strings.HasSuffix(name, ":")
+}
+
+func movelist_header_name(line string) string {
+    // Returns the text of a header without any of its formatting, for comparing headers, e.g. "special moves"
+
+    return strings.ToLower(movelist_header_text(line))
+}
+
+func movelist_header_text(line string) string {
+    // Returns the text of a header without its color or colons, e.g. "Special Moves"
+
+    name := strings.TrimSpace(color_tag_regex.ReplaceAllString(line, ""))
+    return strings.TrimSpace(strings.Trim(name, ":"))
+}
+
+func movelist_entry_name(line string) string {
+    // Returns the name of the move on a movelist line, without any of its formatting (or an empty string for blank lines)
+    // e.g. "    Fireball <#bebebe>(1000)</>			_QCF^A" is "fireball"
+    // a label in brackets at the end is left out too, so "Fireball (Air)" and "Fireball (EX version)" still count as "fireball"
+
+    name := line
+    if i := strings.Index(name, "\t"); i != -1 {
+        name = name[:i]
+    }
+
+    name = color_span_regex.ReplaceAllString(name, "")
+    name = color_tag_regex.ReplaceAllString(name, "")
+    name = strings.TrimSpace(name)
+
+    if i := strings.LastIndex(name, " ("); i > 0 && strings.HasSuffix(name, ")") {
+        name = name[:i]
+    }
+
+    return strings.ToLower(strings.Join(strings.Fields(name), " "))
+}
@@ -0,0 +1,104 @@
+package iguana
+
+import (
+    "strings"
+    "testing"
+)
+
+// the movelist the merge tests add to the existing ones
+const merge_generated = "<#f0f000>:Special Moves:</>\n" +
+    "Fireball\t\t\t_QCF^A\n" +
+    "Kick\t\t\t_QCB^B\n" +
+    "    Kick Follow-Up\t\t\t_QCB^B\n" +
+    "\n" +
+    "<#f0f000>:Hyper Moves:</>\n" +
+    "Super <#bebebe>(1000)</>\t\t\t_QCF_QCF^A\n"
+
+func TestMergeMovelist(t *testing.T) {
+    tests := []struct {
+        name     string
+        existing string
+        want     string
+    }{
+        {
+            "empty",
+            "",
+            "<#f0f000>:Special Moves:</><#000000></>\n" +
+                "Fireball\t\t\t_QCF^A<#000000></>\n" +
+                "Kick\t\t\t_QCB^B<#000000></>\n" +
+                "    Kick Follow-Up\t\t\t_QCB^B<#000000></>\n" +
+                "<#000000></>\n" +
+                "<#f0f000>:Hyper Moves:</><#000000></>\n" +
+                "Super <#bebebe>(1000)</>\t\t\t_QCF_QCF^A<#000000></>\n",
+        },
+        {
+            "hand-written entries are kept",
+            "<#f0f000>:Special Moves:</>\n" +
+                "Fireball (my notes)\t\t\t_QCF^A\n" +
+                "\n" +
+                "<#f0f000>:Hyper Moves:</>\n" +
+                "Super <#bebebe>(1000)</>\t\t\t_QCF_QCF^A or _QCB_QCB^A\n",
+            "<#f0f000>:Special Moves:</>\n" +
+                "Fireball (my notes)\t\t\t_QCF^A\n" +
+                "Kick\t\t\t_QCB^B<#000000></>\n" +
+                "    Kick Follow-Up\t\t\t_QCB^B<#000000></>\n" +
+                "\n" +
+                "<#f0f000>:Hyper Moves:</>\n" +
+                "Super <#bebebe>(1000)</>\t\t\t_QCF_QCF^A or _QCB_QCB^A\n",
+        },
+        {
+            "generated lines are replaced in place",
+            "<#f0f000>:Special Moves:</>\n" +
+                "Kick\t\t\t_B^B<#000000></>\n" +
+                "Fireball\t\t\t_QCF^A\n" +
+                "Old Move\t\t\t_F^A<#000000></>\n" +
+                "\n" +
+                "<#f0f000>:Hyper Moves:</>\n" +
+                "Super\t\t\t_QCF_QCF^A\n",
+            "<#f0f000>:Special Moves:</>\n" +
+                "Kick\t\t\t_QCB^B<#000000></>\n" +
+                "Fireball\t\t\t_QCF^A\n" +
+                "    Kick Follow-Up\t\t\t_QCB^B<#000000></>\n" +
+                "\n" +
+                "<#f0f000>:Hyper Moves:</>\n" +
+                "Super\t\t\t_QCF_QCF^A\n",
+        },
+        {
+            "line endings are kept",
+            "<#f0f000>:Special Moves:</>\r\n" +
+                "Fireball\t\t\t_QCF^A\r\n",
+            "<#f0f000>:Special Moves:</>\r\n" +
+                "Fireball\t\t\t_QCF^A\r\n" +
+                "Kick\t\t\t_QCB^B<#000000></>\r\n" +
+                "    Kick Follow-Up\t\t\t_QCB^B<#000000></>\r\n" +
+                "<#000000></>\r\n" +
+                "<#f0f000>:Hyper Moves:</><#000000></>\r\n" +
+                "Super <#bebebe>(1000)</>\t\t\t_QCF_QCF^A<#000000></>\r\n",
+        },
+    }
+
+    for _, test := range tests {
+        t.Run(test.name, func(t *testing.T) {
+            merged, _ := MergeMovelist(test.existing, merge_generated)
+            if merged != test.want {
+                t.Errorf("got merged movelist:\n%s\nwant:\n%s", merged, test.want)
+            }
+
+            // merging the same moves again shouldn't change anything
+            again, placed := MergeMovelist(merged, merge_generated)
+            if again != merged {
+                t.Errorf("merging twice gave:\n%s\nwant:\n%s", again, merged)
+            }
+
+            // every line that was placed should point at itself in the merged movelist
+            merged_lines := strings.Split(strings.ReplaceAll(again, "\r\n", "\n"), "\n")
+            for i, generated := range strings.Split(merge_generated, "\n") {
+                if i >= len(placed) || placed[i] == 0 {continue}
+
+                if got := merged_lines[placed[i]-1]; strings.ReplaceAll(got, generated_tag, "") != generated {
+                    t.Errorf("line %d of the generated movelist was placed on line %d, which is %q", i+1, placed[i], got)
+                }
+            }
+        })
+    }
+}
@@ -0,0 +1,225 @@
+package iguana
+
+import (
+    "regexp"
+    "strconv"
+    "strings"
+)
+
+// matches the power usage at the end of a move's name, e.g. " <#bebebe>(1000)</>"
+var power_span_regex = regexp.MustCompile(`\s*<#[0-9A-Fa-f]*>\(([^()<]*)\)</>\s*$`)
+
+// matches a glyph at the start of some text, e.g. "_QCF", "^A", "~DF" or "_+"
+var glyph_regex = regexp.MustCompile(`^[_^~](\+|[A-Z]+)`)
+
+// ReadMovelist reads an existing movelist.dat into the same entries Iguana makes from a command file,
+// so that it can be checked, or written out again with different options (see FormatMovelist)
+// charges, released buttons and power usage are recognized using the formats in opt, so it should match what the movelist was made with
+func ReadMovelist(path string, opt Options) ([]MoveEntry, error) {
+    file_data, err := read_file(path)
+    if err != nil {
+        return nil, err
+    }
+
+    return new_converter(opt).parse_movelist(path, string(file_data)), nil
+}
+
+// FormatMovelist writes entries out in the movelist.dat format, the same way a converted command file is
+func FormatMovelist(entries []MoveEntry, opt Options) string {
+    movelist, _ := new_converter(opt).format_move_table(entries)
+    return movelist
+}
+
+// ConvertMovelist rewrites an existing movelist.dat with the given options, without needing the character's command file
+// e.g. to change its colors, or to switch its buttons from A/B/C to LK/MK/HK with UseKP
+// the movelist keeps the line endings it was written with
+func ConvertMovelist(path string, opt Options) (string, error) {
+    file_data, err := read_file(path)
+    if err != nil {
+        return "", err
+    }
+
+    cv := new_converter(opt)
+    movelist, _ := cv.format_move_table(cv.parse_movelist(path, string(file_data)))
+
+    if strings.Contains(string(file_data), "\r\n") {
+        movelist = strings.ReplaceAll(movelist, "\n", "\r\n")
+    }
+
+    return movelist, nil
+}
+
+func (cv *converter) parse_movelist(path string, text string) []MoveEntry {
+    // Parses the text of a movelist.dat, with each line (other than headers and blank lines) becoming its own entry
+
+    var entries []MoveEntry
+    var header string
+
+    for i, line := range split_lines(strings.TrimPrefix(text, "\uFEFF")) {
+        line = strings.TrimSuffix(line, "\r")
+
+        // whether Iguana added a line itself doesn't matter once it's been read
+        line = strings.ReplaceAll(line, generated_tag, "")
+
+        if is_movelist_header(line) {
+            header = movelist_header_text(line)
+            continue
+        }
+
+        if strings.TrimSpace(line) == "" {continue}
+
+        mv := cv.parse_movelist_entry(line)
+        mv.header = header
+        mv.source = Source{File: path, Line: i + 1, Section: header}
+
+        cv.debug("Read movelist entry:", mv.name, mv.variants)
+        entries = append(entries, mv)
+    }
+
+    return entries
+}
+
+func (cv *converter) parse_movelist_entry(line string) MoveEntry {
+    // Parses a single line of a movelist, e.g. "    Fireball <#bebebe>(1000)</>			_QCF^A or _QCB^A"
+    // the name comes before the first tab, and its inputs after the last
+
+    mv := MoveEntry{verbatim: true}
+
+    // follow-ups are indented by four spaces for every level
+    // (any tabs before the name aren't indentation Iguana writes, so they're dropped rather than taken as the end of an empty name)
+    indent := len(line) - len(strings.TrimLeft(line, " "))
+    mv.depth = indent / 4
+    line = strings.TrimLeft(line[indent:], "\t ")
+
+    name, inputs := line, ""
+    if i := strings.Index(line, "\t"); i != -1 {
+        name, inputs = line[:i], strings.Trim(line[i:], "\t ")
+    }
+
+    mv.name, mv.power = cv.parse_power(strings.TrimSpace(name))
+
+    if inputs != "" {
+        for _, alt := range strings.Split(inputs, " " + cv.translate("or") + " ") {
+            mv.variants = append(mv.variants, Variant{command: cv.parse_glyphs(alt)})
+        }
+    }
+
+    return mv
+}
+
+func (cv *converter) parse_power(name string) (string, int) {
+    // Splits a move's name from the power usage written after it, in any of the PowerStyle formats
+    // a bracketed note that isn't power usage (e.g. "<#ff0000>(EX)</>") is left as part of the name
+
+    match := power_span_regex.FindStringSubmatchIndex(name)
+    if match == nil {
+        return name, 0
+    }
+
+    value := strings.TrimSpace(name[match[2]:match[3]])
+    per_bar := cv.opt.PowerPerBar
+
+    if power, err := strconv.Atoi(value); err == nil {
+        return name[:match[0]], power
+    }
+
+    if bars := strings.Count(value, cv.opt.BarGlyph); bars > 0 && strings.Repeat(cv.opt.BarGlyph, bars) == value {
+        return name[:match[0]], bars * per_bar
+    }
+
+    // levels are matched in every language, since they're the only part of power usage that gets translated
+    prefixes := []string{cv.translate("level"), default_translation["level"]}
+    for _, table := range translations {
+        prefixes = append(prefixes, table["level"])
+    }
+
+    for _, prefix := range prefixes {
+        if prefix == "" || !strings.HasPrefix(value, prefix) {continue}
+
+        if level, err := strconv.Atoi(strings.TrimSpace(value[len(prefix):])); err == nil {
+            return name[:match[0]], level * per_bar
+        }
+    }
+
+    return name, 0
+}
+
+func format_regex(format string, placeholders map[string]string) *regexp.Regexp {
+    // Turns one of the annotation formats from Options (e.g. "[{dir}] charge, ") into a regex that matches it at the start of some text
+    // each placeholder is swapped for the pattern of what it stands for
+
+    pattern := regexp.QuoteMeta(format)
+
+    for placeholder, sub_pattern := range placeholders {
+        pattern = strings.ReplaceAll(pattern, regexp.QuoteMeta(placeholder), sub_pattern)
+    }
+
+    return regexp.MustCompile("^" + pattern)
+}
+
+func (cv *converter) parse_glyphs(input string) string {
+    // Converts the glyphs of a single input back into tokens, the reverse of detokenize()
+    // anything that isn't a glyph Iguana knows is kept as a literal token, so that it's written back out exactly as it was
+
+    charge := format_regex(cv.opt.ChargeFormat, map[string]string{"{dir}": `(?P<dir>~(?:DF|DB|UF|UB|D|F|U|B)|\^[A-Z]+)`, "{time}": `(?P<time>[0-9]+)`})
+    release := format_regex(cv.opt.ReleaseFormat, map[string]string{"{button}": `(?P<button>\^[A-Z]+)`})
+    tokens := glyph_tokens()
+
+    var output string
+    var literal string
+
+    add := func(token string) {
+        if literal != "" {
+            output += "`" + strings.ReplaceAll(literal, "`", "") + "`"
+            literal = ""
+        }
+        output += token
+    }
+
+    for pos := 0; pos < len(input); {
+        rest := input[pos:]
+
+        if match := charge.FindStringSubmatch(rest); match != nil && match[0] != "" && charge.SubexpIndex("dir") != -1 {
+            // a charge written without its duration still needs one in its token
+            time := "0"
+            if i := charge.SubexpIndex("time"); i != -1 {
+                time = match[i]
+            }
+
+            // held buttons use the button's own token in place of a direction
+            dir := match[charge.SubexpIndex("dir")]
+            held, ok := charge_directions[strings.TrimPrefix(dir, "~")]
+            if !strings.HasPrefix(dir, "~") {
+                held, ok = tokens[dir]
+            }
+
+            if ok {
+                add("|" + held + time + "|")
+                pos += len(match[0])
+                continue
+            }
+        }
+
+        if match := release.FindStringSubmatch(rest); match != nil && match[0] != "" && release.SubexpIndex("button") != -1 {
+            if token, ok := tokens[match[release.SubexpIndex("button")]]; ok {
+                add("-" + token)
+                pos += len(match[0])
+                continue
+            }
+        }
+
+        if match := glyph_regex.FindString(rest); match != "" {
+            if token, ok := tokens[match]; ok {
+                add(token)
+                pos += len(match)
+                continue
+            }
+        }
+
+        literal += input[pos:pos+1]
+        pos++
+    }
+
+    add("")
+    return output
+}
@@ -0,0 +1,140 @@
+package iguana
+
+import (
+    "os"
+    "path/filepath"
+    "strings"
+    "testing"
+)
+
+func TestReadMovelist(t *testing.T) {
+    // each of these changes the options a movelist is generated with, then checks that reading it back gives the same movelist
+    // rewriting the default movelist with them should also give what converting the .cmd would have, where the options can be read back
+    tests := []struct {
+        name      string
+        options   func(opt *Options)
+        rewritten bool
+    }{
+        {"default", func(opt *Options) {}, true},
+        {"kp buttons", func(opt *Options) {opt.UseKP = true}, true},
+        {"power levels", func(opt *Options) {opt.PowerStyle = PowerStyleLevels}, true},
+        {"power bars", func(opt *Options) {opt.PowerStyle = PowerStyleBars; opt.BarGlyph = "|"}, true},
+        {"colors", func(opt *Options) {opt.HeaderColor = "ff0000"; opt.PowerColor = "00ff00"}, true},
+        {"split variants", func(opt *Options) {opt.SplitVariants = true}, false},
+        {"statetype labels", func(opt *Options) {opt.StateTypes = StateTypesLabel}, false},
+        {"charge and release formats", func(opt *Options) {opt.ChargeFormat = "{dir} for {time}, "; opt.ReleaseFormat = "let go of {button}"}, false},
+        {"spanish", func(opt *Options) {opt.Language = "es"}, false},
+    }
+
+    default_movelist, err := Convert(filepath.Join("res", "test.cmd"), DefaultOptions())
+    if err != nil {
+        t.Fatal(err)
+    }
+
+    default_path := filepath.Join(t.TempDir(), "movelist.dat")
+    if err := os.WriteFile(default_path, []byte(default_movelist), 0644); err != nil {
+        t.Fatal(err)
+    }
+
+    for _, test := range tests {
+        t.Run(test.name, func(t *testing.T) {
+            opt := DefaultOptions()
+            test.options(&opt)
+
+            movelist, err := Convert(filepath.Join("res", "test.cmd"), opt)
+            if err != nil {
+                t.Fatal(err)
+            }
+
+            path := filepath.Join(t.TempDir(), "movelist.dat")
+            if err := os.WriteFile(path, []byte(movelist), 0644); err != nil {
+                t.Fatal(err)
+            }
+
+            if got, err := ConvertMovelist(path, opt); err != nil || got != movelist {
+                t.Errorf("reading the movelist back gave:\n%s\nwant:\n%s\n(error: %v)", got, movelist, err)
+            }
+
+            if !test.rewritten {return}
+
+            if got, err := ConvertMovelist(default_path, opt); err != nil || got != movelist {
+                t.Errorf("rewriting the default movelist gave:\n%s\nwant:\n%s\n(error: %v)", got, movelist, err)
+            }
+        })
+    }
+}
+
+func TestReadHandWrittenMovelist(t *testing.T) {
+    // anything Iguana wouldn't write itself has to survive being read and written back out
+    // (want is left empty when that should give exactly the same movelist)
+    tests := []struct {
+        name     string
+        movelist string
+        want     string
+    }{
+        {"custom header", "<#f0f000>:Normals:</>\nJab\t\t\t^X\n", ""},
+        {"text between glyphs", "<#f0f000>:Special Moves:</>\nRekka\t\t\t_QCF^X (x3)\n", ""},
+        {"unknown glyph", "<#f0f000>:Special Moves:</>\nTaunt\t\t\t^TAUNT\n", ""},
+        {"no inputs", "<#f0f000>:Special Moves:</>\nAuto Guard\n", ""},
+        {"follow-ups", "<#f0f000>:Special Moves:</>\nRekka\t\t\t_QCF^A\n    Second\t\t\t_QCF^A\n        Third\t\t\t_DSF^A\n", ""},
+        {"indented first entry", "<#f0f000>:Special Moves:</>\n        Deep\t\t\t^A\n", ""},
+        {"indented past its parent", "<#f0f000>:Special Moves:</>\nRekka\t\t\t_QCF^A\n        Third\t\t\t_DSF^A\n    Second\t\t\t_QCF^A\n", ""},
+        {"leading tab", "<#f0f000>:Special Moves:</>\n\tTabbed\t\t\t^A\n", "<#f0f000>:Special Moves:</>\nTabbed\t\t\t^A\n"},
+        {"crlf", "<#f0f000>:Special Moves:</>\r\nRekka\t\t\t_QCF^A\r\n    Second\t\t\t_QCF^A\r\n", ""},
+    }
+
+    for _, test := range tests {
+        t.Run(test.name, func(t *testing.T) {
+            path := filepath.Join(t.TempDir(), "movelist.dat")
+            if err := os.WriteFile(path, []byte(test.movelist), 0644); err != nil {
+                t.Fatal(err)
+            }
+
+            want := test.want
+            if want == "" {
+                want = test.movelist
+            }
+
+            if got, err := ConvertMovelist(path, DefaultOptions()); err != nil || got != want {
+                t.Errorf("got movelist:\n%q\nwant:\n%q\n(error: %v)", got, want, err)
+            }
+        })
+    }
+}
+
+func TestReadMovelistEntries(t *testing.T) {
+    movelist := "<#f0f000>:Hyper Moves:</>\n" +
+        "Super <#bebebe>(1000)</>\t\t\t_QCF_QCF^A or [~B] charge, _F^A\n" +
+        "    Follow-Up\t\t\t_QCB^B\n"
+
+    path := filepath.Join(t.TempDir(), "movelist.dat")
+    if err := os.WriteFile(path, []byte(movelist), 0644); err != nil {
+        t.Fatal(err)
+    }
+
+    entries, err := ReadMovelist(path, DefaultOptions())
+    if err != nil {
+        t.Fatal(err)
+    }
+
+    if len(entries) != 2 {
+        t.Fatalf("got %d entries, want 2", len(entries))
+    }
+
+    super, follow_up := entries[0], entries[1]
+    if super.Name() != "Super" || super.Power() != 1000 || super.Header() != "Hyper Moves" || super.Depth() != 0 {
+        t.Errorf("got %q with %d power under %q at depth %d, want \"Super\" with 1000 power under \"Hyper Moves\" at depth 0", super.Name(), super.Power(), super.Header(), super.Depth())
+    }
+
+    var inputs []string
+    for _, v := range super.Variants() {
+        inputs = append(inputs, v.Input(DefaultOptions()))
+    }
+    if got := strings.Join(inputs, ", "); got != "_QCF_QCF^A, [~B] charge, _F^A" {
+        t.Errorf("got inputs %q", got)
+    }
+
+    if follow_up.Name() != "Follow-Up" || follow_up.Depth() != 1 {
+        t.Errorf("got %q at depth %d, want \"Follow-Up\" at depth 1", follow_up.Name(), follow_up.Depth())
+    }
+}
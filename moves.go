@@ -0,0 +1,180 @@
+package iguana
+
+import (
+    "sort"
+    "strconv"
+    "strings"
+)
+
+func (move *Move) add_trigger(number int, tree *expr) {
+    // Adds a line to the given numbered trigger group, creating the group if needed
+
+    for g := range move.groups {
+        if move.groups[g].number == number {
+            move.groups[g].lines = append(move.groups[g].lines, tree)
+            return
+        }
+    }
+
+    move.groups = append(move.groups, trigger_group{number: number, lines: []*expr{tree}})
+    sort.Slice(move.groups, func(a, b int) bool {return move.groups[a].number < move.groups[b].number})
+}
+
+func (move *Move) active_groups() []trigger_group {
+    // Returns the trigger groups MUGEN actually checks
+    // numbering has to start at trigger1 and go up without gaps, so anything after a missing number is ignored
+
+    var output []trigger_group
+
+    for g := range move.groups {
+        if move.groups[g].number != g + 1 {break}
+        output = append(output, move.groups[g])
+    }
+
+    return output
+}
+
+func (move *Move) command_alternatives() [][]string {
+    // Returns every combination of commands that activates this move
+    // every triggerall line has to be true, along with every line of at least one numbered trigger group
+    // commands that are negated (!command = "x", command != "x") don't count as requirements
+
+    alternatives := [][]string{{}}
+
+    for _, tree := range move.triggerall {
+        alternatives = and_alternatives(alternatives, tree.command_alternatives())
+    }
+
+    groups := move.active_groups()
+
+    // plenty of characters (and our own test file) only use triggerall, so no groups at all is treated as one that's always true
+    if len(groups) > 0 {
+        var group_alternatives [][]string
+
+        for _, group := range groups {
+            group_alternatives = append(group_alternatives, group.command_alternatives()...)
+        }
+
+        alternatives = and_alternatives(alternatives, group_alternatives)
+    }
+
+    return clean_alternatives(alternatives)
+}
+
+func (group trigger_group) command_alternatives() [][]string {
+    // Returns every combination of commands that satisfies all of this group's lines
+
+    alternatives := [][]string{{}}
+
+    for _, tree := range group.lines {
+        alternatives = and_alternatives(alternatives, tree.command_alternatives())
+    }
+
+    return alternatives
+}
+
+func (cv *converter) scrape_moves(input *ini_document) []Move {
+    // Returns array of move-structs created from the given INI
+    // This should *only* parse sections after the [Statedef -1] section, up until the next [Statedef] if there is one
+
+    cv.debug("Scraping move state controllers...", "\n"+hr)
+    var moves []Move
+    var statedef_reached = false
+
+    for _, section := range input.sections {
+        // any other [Statedef] ends Statedef -1, which matters for state files where it's followed by regular states
+        if strings.HasPrefix(strings.ToLower(section.name), "statedef ") && !section.is("Statedef -1") {
+            statedef_reached = false
+            continue
+        }
+
+        if statedef_reached {
+            var move Move
+            var is_changestate bool = true
+
+            // trims "State -1," and then trims any whitespace (or uses the comment above it, see label())
+            move.name = section.label()
+            move.source = Source{File: input.path, Line: section.line, Section: section.name}
+
+            // scans over every key in the state controller, in order, including ones that have been written more than once (e.g. multiple triggeralls)
+            for _, key := range section.keys {
+                var key_name, key_value = key.name, key.value
+
+                // checks the type field to make sure that this actually is a move
+                // if it isn't, break the loop and move onto the next section
+                if strings.EqualFold(key_name, "type") {
+                    if !strings.EqualFold(key_value, "ChangeState") {
+                        cv.debug("Move", move.name, "detected as a non-ChangeState type. Discarding...")
+                        is_changestate = false
+                        break
+                    }
+                }
+
+                // the state a move changes to is what links follow-up moves to it
+                if strings.EqualFold(key_name, "value") {
+                    move.value = key_value
+                    continue
+                }
+
+                // only the triggers decide if (and how) a move can be done, so everything else can be skipped
+                var group int
+                var is_triggerall = strings.EqualFold(key_name, "triggerall")
+
+                if !is_triggerall {
+                    if !strings.HasPrefix(strings.ToLower(key_name), "trigger") {continue}
+
+                    number, err := strconv.Atoi(strings.TrimSpace(key_name[len("trigger"):]))
+                    if err != nil || number < 1 {continue}
+                    group = number
+                }
+
+                // parse the trigger into a tree, which gets sorted into either triggerall or its numbered group
+                tree, err := parse_trigger(key_value)
+                if err != nil {
+                    cv.debug("Move trigger", key_value, "couldn't be parsed:", err)
+                    continue
+                }
+
+                if is_triggerall {
+                    move.triggerall = append(move.triggerall, tree)
+                } else {
+                    move.add_trigger(group, tree)
+                }
+            }
+
+            cv.analyze_move(&move)
+            has_command := len(move.commands) > 0
+
+            if is_changestate && has_command {
+                cv.debug("Found move:", move.name, move.commands, "statetype mask:", move.statetypes, "ctrl mask:", move.ctrl, "power:", move.power)
+                moves = append(moves, move)
+            }
+        }
+
+        // Statedef -1 is where moves start being defined, so we ignore everything before that point as an optimization
+        if section.is("Statedef -1") {
+            statedef_reached = true
+        }
+    }
+
+    return moves
+}
+
+func (cv *converter) analyze_move(move *Move) {
+    // Works out everything a move needs from its triggers, once they've all been collected
+
+    move.commands = move.command_alternatives()
+
+    // keep track of what the move needs besides its commands, e.g. being in the air
+    move.statetypes = move.possible_values("statetype", statetype_values)
+    move.ctrl = move.possible_values("ctrl", ctrl_values)
+
+    // triggers that rule out every statetype or ctrl value mean the move can never be done, so it's left out like one without commands
+    if move.statetypes == 0 || move.ctrl == 0 {
+        cv.debug("Move", move.name, "can never be done, skipping...")
+        move.commands = nil
+    }
+
+    // power requirements are what determines if a move is a hyper
+    move.power = move.power_requirement(cv.known)
+}
@@ -0,0 +1,148 @@
+package iguana
+
+import (
+    "strings"
+)
+
+// MUGEN's files look like INI, but don't quite follow its rules, so a generic INI library only mostly works on them
+// only ; starts a comment (and never inside of quotes), keys can repeat (e.g. several triggeralls), the order of keys matters,
+// and section names are written with all sorts of spacing. This reads them the way MUGEN does instead,
+// keeping line numbers and comments around so that moves can be named and traced back to where they came from
+
+// a single "name = value" line
+type ini_key struct {
+    name  string
+    value string
+    line  int // 1-based line number
+}
+
+// a single [section] and every key in it, in the order they were written (duplicates included)
+type ini_section struct {
+    name    string // with its spacing tidied up, e.g. "[ State -1 ,  Fireball ]" becomes "State -1 , Fireball"
+    line    int    // 1-based line number of the header
+    comment string // the comment on the line right above the header, if that line has nothing else on it
+    keys    []ini_key
+}
+
+// a whole parsed file
+type ini_document struct {
+    path     string
+    sections []*ini_section
+}
+
+func parse_mugen_ini(path string, file_data []byte) *ini_document {
+    // Parses a MUGEN INI file (.cmd, .cns, .st...)
+    // there's no such thing as a malformed file here: like MUGEN, anything that isn't a section or a key is just skipped
+
+    doc := &ini_document{path: path}
+    var section *ini_section
+    var prev_comment string
+
+    text := strings.TrimPrefix(string(file_data), "\uFEFF")
+
+    for i, line := range strings.Split(text, "\n") {
+        code, comment := strip_comment(line)
+        code = strings.TrimSpace(code)
+
+        if code == "" {
+            // a line with just a comment might be labelling the section after it, but a blank line in between breaks that
+            if strings.TrimSpace(comment) != "" {
+                prev_comment = strings.TrimSpace(strings.ReplaceAll(comment, ";", ""))
+            } else {
+                prev_comment = ""
+            }
+            continue
+        }
+
+        if strings.HasPrefix(code, "[") {
+            name := code[1:]
+            if end := strings.LastIndex(name, "]"); end != -1 {
+                name = name[:end]
+            }
+
+            section = &ini_section{name: strings.Join(strings.Fields(name), " "), line: i + 1, comment: prev_comment}
+            doc.sections = append(doc.sections, section)
+            prev_comment = ""
+            continue
+        }
+
+        prev_comment = ""
+
+        // keys before the first section don't belong to anything MUGEN reads
+        equals := strings.Index(code, "=")
+        if equals == -1 || section == nil {continue}
+
+        name := strings.TrimSpace(code[:equals])
+        if name == "" {continue}
+
+        section.keys = append(section.keys, ini_key{name: name, value: unquote(strings.TrimSpace(code[equals+1:])), line: i + 1})
+    }
+
+    return doc
+}
+
+func strip_comment(line string) (string, string) {
+    // Splits a line into its code and its comment (which includes the ;), ignoring any ; inside of quotes
+
+    in_quotes := false
+
+    for i := 0; i < len(line); i++ {
+        switch line[i] {
+        case '"':
+            in_quotes = !in_quotes
+        case ';':
+            if !in_quotes {
+                return line[:i], line[i:]
+            }
+        }
+    }
+
+    return line, ""
+}
+
+func unquote(value string) string {
+    // Removes the quotes around a value that's entirely one quoted string, e.g. name = "QCF_x"
+    // values that only contain strings, like triggers, are left alone
+
+    if len(value) >= 2 && value[0] == '"' && value[len(value)-1] == '"' && !strings.Contains(value[1:len(value)-1], `"`) {
+        return value[1 : len(value)-1]
+    }
+
+    return value
+}
+
+func (s *ini_section) is(name string) bool {
+    // Checks if this section has the given name, ignoring case and spacing (so "StateDef  -1" is "Statedef -1")
+
+    return strings.EqualFold(strings.Join(strings.Fields(s.name), ""), strings.Join(strings.Fields(name), ""))
+}
+
+func (s *ini_section) value(name string) string {
+    // Returns the value of the first key with the given name, ignoring case (or an empty string if there's no such key)
+
+    for _, key := range s.keys {
+        if strings.EqualFold(key.name, name) {
+            return key.value
+        }
+    }
+
+    return ""
+}
+
+func (s *ini_section) label() string {
+    // Returns the name of a state controller, e.g. "Fireball" for [State -1, Fireball]
+    // Fighter Factory popularized putting the name in a comment above the controller instead, e.g. "; Fireball" above [State -1, 1]
+    // a very large portion of characters use this comment-as-label layout as a result, so this is pretty important!
+
+    name := strings.TrimSpace(s.name[strings.Index(s.name, ",")+1:])
+
+    // weird heuristic here, we check if what comes after "State" is short enough to not be a real name
+    // the idea is if this ends up being long enough we can safely assume it's got a name of some kind already
+    if s.comment != "" && strings.HasPrefix(strings.ToLower(s.name), "state ") {
+        if len(s.name[len("state "):]) <= 5 {
+            return s.comment
+        }
+    }
+
+    return name
+}
@@ -2,7 +2,7 @@
 ; The purpose of this file is to be used for testing Iguana's basic functionality.
 ; This file will NOT function as an actual CMD file within MUGEN, IKEMEN Go, or any other engine that supports this format.
 
-; Button Remaps (not yet supported by Iguana)
+; Button Remaps
 [Remap]
 x = x
 y = y
@@ -83,6 +83,31 @@ command = F, D, B, U, a
 name = "360Back"
 command = F, U, B, D, a
 
+; Motions without a button, for pairing with one in a trigger
+[Command]
+name = "QCFMotion"
+command = D, DF, F
+
+; Charge inputs
+[Command]
+name = "ChargeBackForward"
+command = ~30$B, F, x
+time = 10
+
+; Release inputs
+[Command]
+name = "ReleaseQuarterCircle"
+command = ~D, DF, F, ~x
+
+[Command]
+name = "HoldRelease"
+command = D, DF, F, ~30a
+
+; Semicolons inside of quotes aren't comments
+[Command]
+name = "Semi;Colon"      ; but this one is
+command = ~F, D, DF, y
+
 ; Defines the start of move definitions
 [Statedef -1]
 
@@ -135,6 +160,11 @@ triggerall = command = "HalfCircleForward"
 triggerall = command = "HalfCircleBack"
 triggerall = power > 1000
 
+[State -1, Move with Power Off by One]
+type = ChangeState
+triggerall = command = "HalfCircleForward"
+triggerall = power > 999
+
 [State -1, 360 Motions]
 type = ChangeState
 triggerall = command = "360Forward"
@@ -142,4 +172,80 @@ triggerall = command = "360Back"
 
 [State -1, Multiple Moves via &&]
 type = ChangeState
-triggerall = command = "x" && command = "y"
\ No newline at end of file
+triggerall = command = "x" && command = "y"
+
+[State -1, Multiple Moves via Split &&]
+type = ChangeState
+triggerall = command = "QCFMotion"
+triggerall = command = "x"
+
+[State -1, Charge Move]
+type = ChangeState
+triggerall = command = "ChargeBackForward"
+
+[State -1, Release Move]
+type = ChangeState
+triggerall = command = "ReleaseQuarterCircle"
+
+[State -1, Held Release Move]
+type = ChangeState
+triggerall = command = "HoldRelease"
+
+[State -1, Alternatives via ||]
+type = ChangeState
+triggerall = command = "QuarterCircleForward" || command = "QuarterCircleBack"
+
+[State -1, Trigger Groups]
+type = ChangeState
+triggerall = statetype != L
+trigger1 = command = "DragonPunchForward"
+trigger1 = statetype = S
+trigger2 = command = "QuarterCircleBack"
+trigger4 = command = "360Forward"
+
+[State -1, Air Move]
+type = ChangeState
+triggerall = command = "QuarterCircleForward"
+trigger1 = statetype = A
+trigger1 = ctrl
+
+[State -1, Ground Move]
+type = ChangeState
+triggerall = command = "QuarterCircleBack"
+triggerall = statetype != A
+trigger1 = ctrl
+
+[State -1, Rekka]
+type = ChangeState
+value = 1000
+triggerall = command = "QuarterCircleForward"
+trigger1 = ctrl
+
+[State -1, Rekka Second Hit]
+type = ChangeState
+value = 1010
+triggerall = command = "QuarterCircleForward"
+trigger1 = stateno = 1000 && movecontact
+
+[State -1, Rekka Third Hit]
+type = ChangeState
+value = 1020
+triggerall = command = "DragonPunchForward"
+trigger1 = stateno = [1010, 1015]
+
+[State -1, Level 3 Move]
+type = ChangeState
+triggerall = command = "360Forward"
+triggerall = 3*1000 <= Power && statetype != A
+
+; Moves whose triggers contradict each other can never be done, so they're left out
+[State -1, Impossible Move]
+type = ChangeState
+triggerall = command = "QuarterCircleForward"
+triggerall = ctrl
+triggerall = ctrl = 0
+
+; Section names with odd spacing still work
+[ State -1 ,   Odd Spacing ]
+type = ChangeState
+triggerall = command = "Semi;Colon"   ; so does a comment after a quoted command
@@ -1,15 +1,14 @@
 package main
 
 import (
+    "encoding/json"
+    "errors"
     "flag"
     "fmt"
     "os"
-    "bufio"
-    "regexp"
-    "strconv"
     "strings"
-    "gopkg.in/ini.v1"
     "path/filepath"
+    "github.com/superfromnd/iguana"
 )
 
 // basic I/O
@@ -22,9 +21,23 @@ var opt_keep1 = false
 var opt_keepai = false
 var opt_usekp = false
 var opt_nomotions = false
+var opt_split = false
 var opt_patchdef = false
 var opt_color_header string
 var opt_color_power string
+var opt_charge_format string
+var opt_release_format string
+var opt_statetypes string
+var opt_power_style string
+var opt_per_bar int
+var opt_bar_glyph string
+var opt_common string
+var opt_lang string
+var opt_text string
+var opt_sources = false
+var opt_backup = false
+var opt_dry_run = false
+var opt_merge = false
 
 // decorative text for the console
 var logo = `
@@ -42,30 +55,16 @@ var hr = "========================================================"
 
 var version string = "(unknown)"
 
-// stores data from [Command] sections
-type Command struct {
-    name    string
-    command string
-    time    int
-}
-
-// stores data from [State -1] sections
-type Move struct {
-    name     string
-    triggers []string
-}
-
-// combined data from the above two, used when creating movelist data file
-type MoveEntry struct {
-    name    string
-    command string
-    held    string
-    power   int
+// a character that couldn't be converted during batch mode
+type failure struct {
+    def string
+    err error
 }
 
 func check_error(err error) {
     if err != nil {
-        panic(err)
+        fmt.Println("Error:", err)
+        os.Exit(1)
     }
 }
 
@@ -87,822 +86,351 @@ func prompt() bool {
     return prompt()
 }
 
-func label_sctrl_with_comment(input []byte) []byte {
-    // Takes an INI file's data and patches all state controllers to use the comment line above them as their name under certain conditions
-    // This practice of labelling was popularized by Fighter Factory, a common tool for character creation
-    // A very large portion of characters use this comment-as-label layout as a result, so this is pretty important!
-
-    str := string(input)
-    str_split := strings.Split(str, "\n")
-    var output string
-    var prev_str string
-
-    for i := range str_split {
-        line := strings.TrimSpace(str_split[i])
-
-        is_statedef := strings.EqualFold(line, "[Statedef -1]")
-        is_state := strings.HasPrefix(strings.ToLower(line), "[s")
-
-        // weird heuristic here, we check if the length of the statedef is higher than a ceratin #
-        // the idea is if this ends up being long enough we can safely assume it's got a name of some kind already
-        // not the most elegant way to check, but it should work well enough hopefully!
-        existing_name_length := len(strings.TrimSpace(strings.ReplaceAll(strings.ToLower(line), "[state ", "")))
-
-        if (strings.HasPrefix(prev_str, ";") && is_state && !is_statedef && existing_name_length <= 6) {
-            name := strings.TrimSpace(strings.ReplaceAll(prev_str, ";", ""))
-            output += "[State -1, " + name + "]" + "\n"
-
-            if opt_debug {fmt.Println("Using FF3 comment label: ", name)}
-        } else {
-            output += str_split[i] + "\n"
-        }
-
-        prev_str = line
+func options() iguana.Options {
+    // Packs the command line options into the struct the conversion library uses
+    opt := iguana.DefaultOptions()
+
+    opt.Debug = opt_debug
+    opt.KeepOneButton = opt_keep1
+    opt.KeepAI = opt_keepai
+    opt.UseKP = opt_usekp
+    opt.NoMotions = opt_nomotions
+    opt.SplitVariants = opt_split
+    opt.PowerPerBar = opt_per_bar
+    opt.BarGlyph = opt_bar_glyph
+
+    switch strings.ToLower(opt_power_style) {
+    case "levels":
+        opt.PowerStyle = iguana.PowerStyleLevels
+    case "bars":
+        opt.PowerStyle = iguana.PowerStyleBars
+    default:
+        opt.PowerStyle = iguana.PowerStyleNumbers
+    }
+
+    switch strings.ToLower(opt_statetypes) {
+    case "label":
+        opt.StateTypes = iguana.StateTypesLabel
+    case "group":
+        opt.StateTypes = iguana.StateTypesGroup
+    default:
+        opt.StateTypes = iguana.StateTypesOff
+    }
+    opt.HeaderColor = opt_color_header
+    opt.PowerColor = opt_color_power
+    opt.ChargeFormat = opt_charge_format
+    opt.ReleaseFormat = opt_release_format
+
+    // -text is either one of the built-in languages, or a translation file of the user's own
+    if info, err := os.Stat(opt_text); opt_text != "" && err == nil && !info.IsDir() {
+        translation, err := iguana.LoadTranslation(opt_text)
+        check_error(err)
+        opt.Translation = translation
+    } else {
+        opt.Language = opt_text
     }
 
-    return []byte(output)
+    return opt
 }
 
-func get_cmd_from_def(input string) string {
-    // gets a path to a command file from a given .def
-    // the .def file is the "root" of a character, and among its data is the path to the command file
-    // so when Iguana is given a .def, we try to use what the .def says is the command file
+func patch_def(def string, lang string) error {
+    // Patches the given .def to use the movelist (of the given language, if any) and reports how it went
 
-    // load the DEF file and parse its INI data
-    file_data, err := os.ReadFile(input)
-    check_error(err)
-
-    parsed_ini, err := ini.LoadSources(ini.LoadOptions{AllowNonUniqueSections: true, SkipUnrecognizableLines: true}, file_data)
-    check_error(err)
-
-    for s := range parsed_ini.Sections() {
-        var sect_name = parsed_ini.Sections()[s].Name()
-
-        // find the Files section
-        if strings.EqualFold(sect_name, "Files") {
-            for k := range parsed_ini.Sections()[s].Keys() {
-                var key_name = parsed_ini.Sections()[s].KeyStrings()[k]
+    fmt.Println("Patching DEF file: ", def)
+    patch_opt := iguana.PatchOptions{Language: lang, Backup: opt_backup}
 
-                // get the value of cmd and use it as the input file
-                // note the Join() here; we convert the path to an absolute to prevent ambiguity
-                if strings.EqualFold(key_name, "cmd") {
-                    return filepath.Join(filepath.Dir(input), parsed_ini.Sections()[s].Key(key_name).String())
-                }
-            }
+    if opt_dry_run {
+        patched, changed, err := iguana.PreviewPatchDef(def, localized_output(lang), patch_opt)
+        if err != nil || !changed {
+            return err
         }
+        return write_output(def, patched)
     }
 
-    fmt.Println("Iguana wasn't able to find a command file from this DEF.")
-    return input
-}
-
-func patch_def(def string) {
-    // loads a given def and patches it to include a movelist.dat
-    // this function runs under the assumption that the .cmd specified by the file
-    // is *also* the location where the movelist is located (which via Iguana is always the case)
-
-    fmt.Println("Patching DEF file: ", def)
-
-    // load the DEF file and parse its INI data
-    file_data, err := os.ReadFile(def)
-    check_error(err)
-
-    parsed_ini, err := ini.LoadSources(ini.LoadOptions{AllowNonUniqueSections: true, IgnoreInlineComment: true, SkipUnrecognizableLines: true}, file_data)
-    check_error(err)
-
-    for s := range parsed_ini.Sections() {
-        var sect_name = parsed_ini.Sections()[s].Name()
-
-        // find the Files section
-        if strings.EqualFold(sect_name, "Files") {
-            for k := range parsed_ini.Sections()[s].Keys() {
-                var key_name = parsed_ini.Sections()[s].KeyStrings()[k]
-                var has_movelist = parsed_ini.Sections()[s].HasKey("movelist")
-
-                if strings.EqualFold(key_name, "cmd") {
-                    // get the value of cmd
-                    cmd_value := parsed_ini.Sections()[s].Key(key_name).String()
-
-                    // strip the cmd value to just the path, and then add the output filename to it
-                    dat_value := filepath.Join(filepath.Dir(cmd_value), output_file)
-
-                    // add our new path to the INI and save it
-                    // this code doesn't work as expected with our INI library, so we have to do a roundabout manual edit instead
-                    //parsed_ini.Sections()[s].NewKey("movelist", dat_value)
-                    //parsed_ini.SaveTo(def)
-
-                    // re-read our def file as individual lines
-                    def_file, _ := os.Open(def)
-                    scanner := bufio.NewScanner(def_file)
-                    file_lines := []string{}
-                    newfile_lines := []string{}
-
-                    for scanner.Scan() {
-                        file_lines = append(file_lines, scanner.Text())
-                    }
-
-                    // append movelist value to the [Files] section
-                    for _, line_data := range file_lines {
-                        newfile_lines = append(newfile_lines, line_data)
-                        if strings.EqualFold(line_data, "[Files]") && !has_movelist {
-                            newfile_lines = append(newfile_lines, "movelist = " + dat_value)
-                        }
-                    }
-
-                    // save the file
-                    write_to, _ := os.Create(def)
-                    writer := bufio.NewWriter(write_to)
-
-                    for _, line := range newfile_lines {
-                        writer.WriteString(line + "\n")
-                    }
-                    writer.Flush()
+    patched, err := iguana.PatchDef(def, localized_output(lang), patch_opt)
+    if err != nil {
+        return err
+    }
 
-                    fmt.Println("Patched successfully!")
-                    return
-                }
-            }
-        }
+    if patched {
+        fmt.Println("Patched successfully!")
+    } else {
+        fmt.Println("This DEF already uses the movelist, or has no command file. No changes have been made.")
     }
 
-    fmt.Println("No command file found in this DEF. No changes have been made.")
-    return
+    return nil
 }
 
-func trim_command(input string) string {
-    // Strips down Command="cmdinput" to just cmdinput
+func write_output(path string, data []byte) error {
+    // Saves a file, or with -dry-run, prints how it would've changed instead
 
-    if !strings.Contains(strings.ToLower(input), "command") {
-        return input
+    if !opt_dry_run {
+        return os.WriteFile(path, data, 0666)
     }
 
-    start := strings.Index(strings.ToLower(input), `command="`) + 9
-    end := strings.Index(input[start:], `"`) + start
-
-    return input[start:end]
-}
-
-func reverse(input string) string {
-    // Flips the given string around
-
-    var output string
+    // a file that doesn't exist yet is diffed against nothing at all
+    old, err := os.ReadFile(path)
+    if err != nil && !os.IsNotExist(err) {
+        return err
+    }
 
-    for _, i := range input {
-        output = string(i) + output
+    diff := iguana.Diff(path, old, data)
+    if diff == "" {
+        fmt.Println("No changes to " + path)
+        return nil
     }
 
-    return output
+    fmt.Print(diff)
+    return nil
 }
 
-func remove_element(input []string, index int) []string {
-    // Returns an array minus the given element
-    j := 0
+func def_languages(def string) []string {
+    // Returns the languages to make movelists for from the given .def, following -lang
+    // the empty string stands for the .def's regular files
 
-    for i := range input {
-        if i != index {
-            input[j] = input[i]
-            j++
-        }
+    switch {
+    case opt_lang == "":
+        return []string{""}
+    case strings.EqualFold(opt_lang, "all"):
+        // a .def that can't be read will fail again once it's loaded, which is where the error gets reported
+        languages, _ := iguana.DefLanguages(def)
+        return append([]string{""}, languages...)
     }
 
-    output := input[:j]
-    return output
+    return []string{strings.ToLower(opt_lang)}
 }
 
-func merge(input []string) string {
-    // Attempt to merge the given strings into one
-    // Used in assemble_movelist(), notoriously unstable
-
-    if opt_debug {
-        fmt.Println("Attempting to merge commands:", input)
-    }
+func localized_output(lang string) string {
+    // Returns the movelist filename for the given language, e.g. movelist.ja.dat
 
-    // determine which one of the commands is the longest
-    longest_entry := 0
-    var output string
-
-    for e := range input {
-        if len(input[e]) > longest_entry {
-            longest_entry = e
-        }
+    if lang == "" {
+        return output_file
     }
 
-    // iterate every character in the longest command (to ensure max coverage)
-    for c := 0; c < len(input[longest_entry]); c++ {
-        var compared_letter string
-
-        // iterate over every entry in the array, getting the character at index C
-        for e := range input {
-            var current_char string = ""
-
-            // safeguard to prevent OOB read crash
-            if len(input[e]) >= len(input[longest_entry]) {
-                current_char = input[e][c : c+1]
-            }
-
-            // fills the string above with *something* if it's blank
-            if compared_letter == "" {
-                compared_letter = current_char
-                continue
-            }
-
-            // if this new character isn't a match with our current one, and isn't already in the string, append it
-            if compared_letter != current_char && current_char != "" {
-                if !strings.Contains(compared_letter, current_char) {compared_letter += "/" + current_char}
-            }
-        }
-
-        output += compared_letter
-    }
+    ext := filepath.Ext(output_file)
+    return strings.TrimSuffix(output_file, ext) + "." + lang + ext
+}
 
-    // cleanup string by converting multiple repeated /'s to a single one
-    extraslash_regex, _ := regexp.Compile("/{2,}")
-    output = extraslash_regex.ReplaceAllString(output, "/")
+func common_commands(char *iguana.Character) {
+    // Points the character at the common command files given with -common, instead of the ones found on their own
+    // either a common.cmd or an Ikemen GO folder can be given, and "none" leaves them out entirely
 
-    if opt_debug {
-        fmt.Println("Merged as:", output)
+    switch {
+    case opt_common == "":
+        return
+    case strings.EqualFold(opt_common, "none"):
+        char.Common = nil
+        return
     }
 
-    return output
-}
-
-func validate_hex_color(output string) string {
-    // Checks if the given string is a valid hex string
-    // The returned string should be A. either three or six characters long and B. consist only of hexadecimal (0-F)
-
-    // this regex matches any string containing lowercase hex digits AND only in pairs of 3 or 6
-    hexcolor_regex, _ := regexp.Compile("^([0-9a-f]{3}){1,2}$")
+    info, err := os.Stat(opt_common)
+    check_error(err)
 
-    if hexcolor_regex.MatchString(strings.ToLower(output)) {
-        return output
+    if info.IsDir() {
+        char.Common = iguana.FindCommonCommands(opt_common)
     } else {
-        // return magenta if the color is invalid hex
-        return "ff00ff"
+        char.Common = []string{opt_common}
     }
 }
 
-func tokenize(input string) string {
-    // Tokenizes the command string, replacing each multi-char button input with a single character
-    // this is done so that merging them becomes easier
-
-    // strips charge input notation (a ~ character, and optionally a value right after it)
-    // Ikemen movelists don't appear to have a standard glyph for marking charge attacks,
-    // so we just represent them as standard inputs, inaccurate as they may be
-    charge_regex, _ := regexp.Compile("~([0-9]*)")
-    input = charge_regex.ReplaceAllString(input, "")
-
-    // > indicates to not press any button betwen previous and next command, unnecessary for us so strip it
-    input = strings.ReplaceAll(input, ">", "")
-
-    // $ indicates to read a direction as 4-way, unnecessary for us so strip it
-    input = strings.ReplaceAll(input, "$", "")
-
-    var split_strings []string = strings.Split(input, ",")
-    var output string
-
-    // split up button inputs strings and concatenate them into a new string
-    // this is so we can use more ASCII characters for tokens without causing conflicts
-    for i := range split_strings {
-        str := split_strings[i]
-
-        // tokenize any held inputs into single characters
-        // note the specific characters we're using for tokenizing here are ASCII-compliant
-        str = strings.ReplaceAll(str, "/DF", "!")
-        str = strings.ReplaceAll(str, "/DB", "@")
-        str = strings.ReplaceAll(str, "/UF", "#")
-        str = strings.ReplaceAll(str, "/UB", "$")
-        str = strings.ReplaceAll(str, "/D", "%")
-        str = strings.ReplaceAll(str, "/F", "^")
-        str = strings.ReplaceAll(str, "/U", "&")
-        str = strings.ReplaceAll(str, "/B", "*")
-        str = strings.ReplaceAll(str, "/a", "(")
-        str = strings.ReplaceAll(str, "/b", ")")
-        str = strings.ReplaceAll(str, "/c", "<")
-        str = strings.ReplaceAll(str, "/x", ">")
-        str = strings.ReplaceAll(str, "/y", ";")
-        str = strings.ReplaceAll(str, "/z", "'")
-        str = strings.ReplaceAll(str, "/s", "{")
-        str = strings.ReplaceAll(str, "/d", "?")
-        str = strings.ReplaceAll(str, "/w", "=")
-
-        // tokenize regular directional inputs
-        str = strings.ReplaceAll(str, "DF", "3")
-        str = strings.ReplaceAll(str, "DB", "1")
-        str = strings.ReplaceAll(str, "UF", "9")
-        str = strings.ReplaceAll(str, "UB", "7")
-        str = strings.ReplaceAll(str, "D", "2")
-        str = strings.ReplaceAll(str, "F", "6")
-        str = strings.ReplaceAll(str, "U", "8")
-        str = strings.ReplaceAll(str, "B", "4")
-
-        output += str
-    }
+func merge_existing(path string, movelist string, sources []iguana.LineSource) (string, []iguana.LineSource, error) {
+    // With -merge, keeps what's already in the movelist at the given path and only adds the moves it's missing
+    // the sources are moved along with their lines, and dropped for lines that didn't make it in
 
-    if !opt_nomotions {
-        // full circles
-        output = strings.ReplaceAll(output, "21478963", "v")
-        output = strings.ReplaceAll(output, "23698741", "n")
-        output = strings.ReplaceAll(output, "89632147", "V")
-        output = strings.ReplaceAll(output, "87412369", "N")
-
-        // full circles (simplified)
-        output = strings.ReplaceAll(output, "2486", "v")
-        output = strings.ReplaceAll(output, "2684", "n")
-        output = strings.ReplaceAll(output, "8624", "V")
-        output = strings.ReplaceAll(output, "8426", "N")
-
-        // full circles (truncated variations)
-        output = strings.ReplaceAll(output, "6248", "V")
-        output = strings.ReplaceAll(output, "4862", "V")
-        output = strings.ReplaceAll(output, "6842", "n")
-        output = strings.ReplaceAll(output, "4268", "n")
-
-        // half circles
-        output = strings.ReplaceAll(output, "47896", "f")
-        output = strings.ReplaceAll(output, "41236", "g")
-        output = strings.ReplaceAll(output, "63214", "h")
-        output = strings.ReplaceAll(output, "69874", "j")
-
-        // quarter circles
-        output = strings.ReplaceAll(output, "236", "q")
-        output = strings.ReplaceAll(output, "698", "W")
-        output = strings.ReplaceAll(output, "874", "e")
-        output = strings.ReplaceAll(output, "412", "r")
-        output = strings.ReplaceAll(output, "214", "t")
-        output = strings.ReplaceAll(output, "478", "Y")
-        output = strings.ReplaceAll(output, "896", "u")
-        output = strings.ReplaceAll(output, "632", "i")
-
-        // dragon punch / z-motion / shoryu / whatever else these are called
-        output = strings.ReplaceAll(output, "623", "o")
-        output = strings.ReplaceAll(output, "421", "p")
-
-        // double-taps
-        output = strings.ReplaceAll(output, "66", "k")
-        output = strings.ReplaceAll(output, "44", "l")
+    if !opt_merge {
+        return movelist, sources, nil
     }
 
-    return output
-}
-
-func detokenize(output string) string {
-    // Converts command from a MoveEntry string into movelist.dat glyphs
-
-    // detokenize held directions
-    output = strings.ReplaceAll(output, "!", "~DF")
-    output = strings.ReplaceAll(output, "@", "~DB")
-    output = strings.ReplaceAll(output, "#", "~UF")
-    output = strings.ReplaceAll(output, "$", "~UB")
-    output = strings.ReplaceAll(output, "%", "~D")
-    output = strings.ReplaceAll(output, "^", "~F")
-    output = strings.ReplaceAll(output, "&", "~U")
-    output = strings.ReplaceAll(output, "*", "~B")
-    output = strings.ReplaceAll(output, "(", "a")
-    output = strings.ReplaceAll(output, ")", "b")
-    output = strings.ReplaceAll(output, "<", "c")
-    output = strings.ReplaceAll(output, ">", "x")
-    output = strings.ReplaceAll(output, ";", "y")
-    output = strings.ReplaceAll(output, "'", "z")
-    output = strings.ReplaceAll(output, "{", "s")
-    output = strings.ReplaceAll(output, "?", "d")
-    output = strings.ReplaceAll(output, "=", "w")
-
-    // convert groups of tokens as motion inputs
-    // the order of replacement here matters; longer substrings get matched first to prevent weirdness
-    // full circles
-    output = strings.ReplaceAll(output, "v", "_FDF")
-    output = strings.ReplaceAll(output, "n", "_FDB")
-    output = strings.ReplaceAll(output, "V", "_FUF")
-    output = strings.ReplaceAll(output, "N", "_FUB")
-
-    // half circles
-    output = strings.ReplaceAll(output, "f", "_HUF")
-    output = strings.ReplaceAll(output, "g", "_HCF")
-    output = strings.ReplaceAll(output, "h", "_HCB")
-    output = strings.ReplaceAll(output, "j", "_HUB")
-
-    // quarter circles
-    output = strings.ReplaceAll(output, "q", "_QCF")
-    output = strings.ReplaceAll(output, "W", "_QFU")
-    output = strings.ReplaceAll(output, "e", "_QUB")
-    output = strings.ReplaceAll(output, "r", "_QBD")
-    output = strings.ReplaceAll(output, "t", "_QCB")
-    output = strings.ReplaceAll(output, "Y", "_QBU")
-    output = strings.ReplaceAll(output, "u", "_QUF")
-    output = strings.ReplaceAll(output, "i", "_QFD")
-
-    // dragon punch / z-motion / shoryu / whatever else these are called
-    output = strings.ReplaceAll(output, "o", "_DSF")
-    output = strings.ReplaceAll(output, "p", "_DSB")
-
-    // double-taps
-    output = strings.ReplaceAll(output, "k", "_XFF")
-    output = strings.ReplaceAll(output, "l", "_XBB")
-
-    // detokenize regular directions and buttons
-    output = strings.ReplaceAll(output, "3", "_DF")
-    output = strings.ReplaceAll(output, "1", "_DB")
-    output = strings.ReplaceAll(output, "9", "_UF")
-    output = strings.ReplaceAll(output, "7", "_UB")
-    output = strings.ReplaceAll(output, "2", "_D")
-    output = strings.ReplaceAll(output, "6", "_F")
-    output = strings.ReplaceAll(output, "8", "_U")
-    output = strings.ReplaceAll(output, "4", "_B")
-
-    if opt_usekp {
-        // use fighting-game-specific button labels
-        output = strings.ReplaceAll(output, "a", "^LK")
-        output = strings.ReplaceAll(output, "b", "^MK")
-        output = strings.ReplaceAll(output, "c", "^HK")
-        output = strings.ReplaceAll(output, "x", "^LP")
-        output = strings.ReplaceAll(output, "y", "^MP")
-        output = strings.ReplaceAll(output, "z", "^HP")
-    } else {
-        // use standard button labels
-        output = strings.ReplaceAll(output, "a", "^A")
-        output = strings.ReplaceAll(output, "b", "^B")
-        output = strings.ReplaceAll(output, "c", "^C")
-        output = strings.ReplaceAll(output, "x", "^X")
-        output = strings.ReplaceAll(output, "y", "^Y")
-        output = strings.ReplaceAll(output, "z", "^Z")
+    existing, err := os.ReadFile(path)
+    if err != nil && !os.IsNotExist(err) {
+        return "", nil, err
     }
 
-    // these buttons lack FG-specific equivalents
-    output = strings.ReplaceAll(output, "s", "^S")
-    output = strings.ReplaceAll(output, "d", "^D")
-    output = strings.ReplaceAll(output, "w", "^W")
+    merged, placed := iguana.MergeMovelist(string(existing), movelist)
 
-    // other special glyphs
-    output = strings.ReplaceAll(output, "+", "_+")
+    var merged_sources []iguana.LineSource
+    for _, source := range sources {
+        if source.Line < 1 || source.Line > len(placed) || placed[source.Line-1] == 0 {continue}
+        source.Line = placed[source.Line-1]
+        merged_sources = append(merged_sources, source)
+    }
 
-    return output
+    return merged, merged_sources, nil
 }
 
+func write_sources(movelist_path string, sources []iguana.LineSource) error {
+    // Saves where each line of a movelist came from next to it, e.g. movelist.sources.json for movelist.dat
 
-func detect_ai_command(c Command) bool {
-    // Checks if the given input command is a WinMUGEN-style AI command
-    // WinMUGEN did not yet include a trigger for checking if a character was AI-controlled,
-    // so a workaround many authors used was to make commands impossible for a human to input,
-    // the idea being that MUGEN's AI could just "activate" it on a whim without actually inputting the command
-
-    // tokenizes the input so 1 input = 1 character
-    // we also strip out any formatting/padding
-    cmd_str := tokenize(c.command)
-    cmd_str = strings.ReplaceAll(cmd_str, "/", "")
-    cmd_str = strings.ReplaceAll(cmd_str, "+", "")
-
-    // the length of this string should be roughly equal to the number of inputs the game has to detect for this command
-    button_count := len(cmd_str)
-
-    // a zero-time command is physically impossible to do
-    if (c.time == 0) {return true}
-
-    // checks if there's more buttons to press than the number of frames where inputs are read as part of a command
-    if (c.time < button_count) {return true}
-
-    return false
-}
+    if !opt_sources {
+        return nil
+    }
 
-func scrape_commands(input *ini.File) []Command {
-    // Returns array of command-structs created from the given INI
-    // This should *only* parse sections named "Command" (case insensitive)
+    data, err := json.MarshalIndent(sources, "", "  ")
+    if err != nil {
+        return err
+    }
 
-    if opt_debug {
-        fmt.Println("Scraping command entries...", "\n"+hr)
+    path := strings.TrimSuffix(movelist_path, filepath.Ext(movelist_path)) + ".sources.json"
+    if !opt_dry_run {
+        fmt.Println("Saving line sources to path: " + path)
     }
-    var cmdlist []Command
-    var default_cmd_time int = 15
 
-    for s := range input.Sections() {
-        var sect_name = input.Sections()[s].Name()
+    return write_output(path, data)
+}
 
-        // check for Defaults section and get default command time
-        if strings.EqualFold(sect_name, "Defaults") {
-            for k := range input.Sections()[s].Keys() {
-                var key_name = input.Sections()[s].KeyStrings()[k]
+func convert_def(def string) error {
+    // Converts the command file of a single .def during batch mode, once for each language asked for
 
-                if strings.EqualFold(key_name, "command.time") {
-                    default_cmd_time, _ = input.Sections()[s].Key(key_name).Int()
-                }
-            }
+    for _, lang := range def_languages(def) {
+        char, err := iguana.LoadDefLanguage(def, lang)
+        if err != nil {
+            return err
         }
+        common_commands(&char)
+        f := char.Cmd
 
-        if strings.EqualFold(sect_name, "Command") {
-            var cmd Command
-
-            cmd.time = default_cmd_time
-
-            for k := range input.Sections()[s].Keys() {
-                var key_name = input.Sections()[s].KeyStrings()[k]
+        fmt.Println("Converting file: " + f)
+        movelist, sources, err := iguana.ConvertCharacterWithSources(char, options())
+        if err != nil {
+            return err
+        }
 
-                if strings.EqualFold(key_name, "name") {
-                    cmd.name = input.Sections()[s].Key(key_name).String()
-                }
+        if opt_debug {
+            fmt.Println("Dump of movelist:\n" + movelist)
+            continue
+        }
 
-                if strings.EqualFold(key_name, "command") {
-                    // strip out all spaces from the command internally, done to make comparisons easier (see assemble_move_table() below)
-                    cmd.command = strings.ReplaceAll(input.Sections()[s].Key(key_name).String(), " ", "")
-                }
+        path := filepath.Dir(f) + "/" + localized_output(lang)
+        movelist, sources, err = merge_existing(path, movelist, sources)
+        if err != nil {
+            return err
+        }
 
-                // amount of time the command must be performed in, used to check for possible AI commands
-                if strings.EqualFold(key_name, "time") {
-                    cmd.time, _ = input.Sections()[s].Key(key_name).Int()
-                }
-            }
+        err = write_output(path, []byte(movelist))
+        if err != nil {
+            return err
+        }
 
-            if opt_debug {
-                fmt.Println("Found command:", cmd)
-            }
-            cmdlist = append(cmdlist, cmd)
+        if err := write_sources(path, sources); err != nil {
+            return err
         }
 
-        // Statedef -1 marks the end of [Command] blocks, so once we reach that, end the loop
-        if strings.EqualFold(sect_name, "Statedef -1") {
-            if opt_debug {
-                fmt.Println("Reached Statedef -1. Ending command scraping...")
+        if (opt_patchdef) {
+            if err := patch_def(def, lang); err != nil {
+                return err
             }
-            break
         }
     }
 
-    return cmdlist
+    return nil
 }
 
-func scrape_moves(input *ini.File) []Move {
-    // Returns array of move-structs created from the given INI
-    // This should *only* parse sections after the [Statedef -1] section
+func convert_movelist(path string) error {
+    // Rewrites an existing movelist with the options given, e.g. to recolor it or switch its buttons with -kp
 
-    if opt_debug {
-        fmt.Println("Scraping move state controllers...", "\n"+hr)
-    }
-    var moves []Move
-    var statedef_reached = false
-
-    for s := range input.Sections() {
-        var sect_name = input.Sections()[s].Name()
-
-        if statedef_reached {
-            var move Move
-            var is_changestate bool = true
-            var has_command = false
-
-            // trims "State -1," and then trims any whitespace
-            move.name = strings.TrimSpace(sect_name[strings.Index(sect_name, ",")+1:])
-
-            // scans over every key in the state controller
-            for k := range input.Sections()[s].Keys() {
-                var key_name = input.Sections()[s].KeyStrings()[k]
-
-                // this extra range loop is necessary to parse "shadow keys", what the INI lib calls multiple keys with identical names (e.g. multiple triggeralls)
-                for v := range input.Sections()[s].Key(key_name).StringsWithShadows("\n") {
-                    var key_value = input.Sections()[s].Key(key_name).StringsWithShadows("\n")[v]
-
-                    // checks the type field to make sure that this actually is a move
-                    // if it isn't, break the loop and move onto the next section
-                    if strings.EqualFold(key_name, "type") {
-                        if !strings.EqualFold(key_value, "ChangeState") {
-                            if opt_debug {
-                                fmt.Println("Move", move.name, "detected as a non-ChangeState type. Discarding...")
-                            }
-                            is_changestate = false
-                            break
-                        }
-                    }
-
-                    // checks the keys to see if they contain a command or a trigger related to power
-                    // the latter is what determines if a move is a hyper
-                    var trigger_has_command bool = strings.Contains(strings.ToLower(key_value), strings.ToLower("command"))
-                    var trigger_has_power bool = strings.Contains(strings.ToLower(key_value), strings.ToLower("power"))
-
-                    // check to make sure the command doesn't have the "not equals" operator (effectively rendering it null for us)
-                    if trigger_has_command {
-                        if strings.Contains(strings.ReplaceAll(strings.ToLower(key_value), " ", ""), strings.ToLower("!=")) {
-                            if opt_debug {
-                                fmt.Println("Move trigger", key_value, "detected as not-equals op. Discarding...")
-                            }
-                            trigger_has_command = false
-                        }
-
-                        has_command = true
-                    }
-
-                    if trigger_has_command || trigger_has_power {
-                        // we also strip out spaces for the triggers here, to make command detection easier
-                        move.triggers = append(move.triggers, strings.ReplaceAll(key_value, " ", ""))
-                    }
-                }
-            }
-
-            if is_changestate && has_command {
-                if opt_debug {
-                    fmt.Println("Found move:", move)
-                }
-                moves = append(moves, move)
-            }
-        }
-
-        // Statedef -1 is where moves start being defined, so we ignore everything before that point as an optimization
-        if strings.EqualFold(sect_name, "Statedef -1") {
-            statedef_reached = true
-        }
+    fmt.Println("Reading movelist: " + path)
+    movelist, err := iguana.ConvertMovelist(path, options())
+    if err != nil {
+        return err
     }
 
-    return moves
-}
-
-func assemble_move_table(commands []Command, moves []Move) []MoveEntry {
-    // Takes commands and moves, then converts them to entries in an array
-    // The returned array is then formatted by format_move_table() and saved to disk
-
     if opt_debug {
-        fmt.Println("Assembling move table...", "\n"+hr)
+        fmt.Println("Dump of movelist:\n" + movelist)
+        return nil
     }
-    var movelist []MoveEntry
 
-    for m := range moves {
-        var mv MoveEntry
-        var cmds []string
-        var cmd_str string
+    output := filepath.Dir(path) + "/" + output_file
+    if !opt_dry_run {
+        fmt.Println("Saving to path: " + output)
+    }
 
-        mv.name = moves[m].name
-        if opt_debug {
-            fmt.Println("Reading move:", mv.name)
-        }
+    return write_output(output, []byte(movelist))
+}
 
-        for t := range moves[m].triggers {
-            move_command := trim_command(moves[m].triggers[t])
+func lint(paths []string) {
+    // Checks the movelists of every .def in the given folders (or the given .def and movelist files), then exits
+    // any problem at all makes Iguana exit with 1, so that it can be used to check a roster automatically
 
-            for c := range commands {
-                // checks if the current trigger has a corresponding command
-                if move_command == commands[c].name {
-                    if (!opt_keepai && detect_ai_command(commands[c])) {
-                        if opt_debug {
-                            fmt.Println("Command detected as AI-only:", move_command)
-                        }
-                    } else {
-                        if opt_debug {
-                            fmt.Println("Tokenizing string:", commands[c].command)
-                        }
+    var problems []iguana.Problem
+    var failures []failure
+    checked := 0
 
-                        command_text := tokenize(commands[c].command)
+    // movelists that have already been checked, so that one shared by several .defs doesn't have its problems listed twice
+    movelists := make(map[string]bool)
 
-                        if opt_debug {
-                            fmt.Println("Tokenized:", command_text)
-                        }
-                        cmds = append(cmds, command_text)
-                    }
-                }
+    for _, input := range paths {
+        filepath.Walk(input, func(path string, info os.FileInfo, err error) error {
+            if err != nil {
+                failures = append(failures, failure{path, err})
+                return nil
             }
 
-            // detect power requirements and pack them into the move entry
-            if strings.Contains(strings.ToLower(move_command), "power") {
-                if opt_debug {
-                    fmt.Println("Power requirement detected:", move_command)
-                }
-
-                trim_amount := 6
-                if strings.Contains(strings.ToLower(move_command), ">=") {
-                    trim_amount++
-                }
-
-                pow, err := strconv.ParseInt(move_command[trim_amount:], 10, 16)
-                if err != nil {
-                    pow = 0
-                }
-
-                mv.power = int(pow)
-            }
-        }
+            var found []iguana.Problem
 
-        // separate the held-input tokens from the regular tokens
-        // held inputs are traditionally kept at the beginning of a command, so we put them into an array to read off later
-        var holding []string
-        var nonholding []string
-        token_regex, _ := regexp.Compile("[!@#$%^&*()[\\];'.~>]")
+            switch strings.ToLower(filepath.Ext(path)) {
+            case ".def":
+                found, err = iguana.LintDef(path, movelists, options())
+            case ".dat":
+                // movelists in a folder are checked through the .def using them, so only ones given directly are checked alone
+                if path != input {return nil}
 
-        for e := range cmds {
-            if len(cmds[e]) == 1 && token_regex.MatchString(cmds[e][0:1]) {
-                holding = append(holding, cmds[e])
-            } else {
-                nonholding = append(nonholding, cmds[e])
-            }
-        }
-        if len(nonholding) == 0 {
-            // do nothing
-        } else if len(nonholding) == 1 {
-            // there's only one command, so just use it verbatim
-            cmd_str = nonholding[0]
-        } else {
-            // heuristic: if there's only two non-held commands that are identical flipped, return one of them
-            // this specifically is meant to catch KFM's blocking inputs among other similar commands
-            if len(nonholding) == 2 && nonholding[0] == reverse(nonholding[1]) {
-                if opt_debug {
-                    fmt.Println("Commands detected to be identical when mirrored. Returning one of them...")
+                if abs, err := filepath.Abs(path); err == nil {
+                    if movelists[abs] {return nil}
+                    movelists[abs] = true
                 }
-                cmd_str = nonholding[0]
-            } else {
-                // attempt to merge the commands (probably the most inaccurate part of the entire program, thus avoided when possible)
-                cmd_str = merge(nonholding)
-            }
-        }
-
-        if holding != nil {
-            if opt_debug {
-                fmt.Println("Held inputs found:", holding)
+                found, err = iguana.LintMovelist(path, iguana.Character{}, options())
+            default:
+                return nil
             }
-            mv.held = fmt.Sprintf("%s", holding)
-        }
 
-        // fallback if, for whatever reason, we end up with no inputs at all
-        if cmd_str == "" && holding == nil {
-            if opt_debug {
-                fmt.Println("Move", mv.name, "has no usable commands. Discarding...")
+            checked++
+            problems = append(problems, found...)
+            if err != nil {
+                failures = append(failures, failure{path, err})
             }
-            continue
-        }
-
-        mv.command = cmd_str
-        movelist = append(movelist, mv)
+            return nil
+        })
     }
 
-    return movelist
-}
-
-func format_move_table(move_table []MoveEntry) string {
-    // Takes a given array of moves and formats it into movelist.dat's formatting
-    // What this function returns is then saved to disk
-
-    if opt_debug {
-        fmt.Println("Formatting move table...", "\n"+hr)
+    for _, p := range problems {
+        fmt.Println(p)
     }
 
-    opt_color_header = validate_hex_color(opt_color_header)
-    opt_color_power = validate_hex_color(opt_color_power)
-
-    special_list := "<#" + opt_color_header + ">:Special Moves:</>\n"
-    hypers_list := "<#" + opt_color_header + ">:Hyper Moves:</>\n"
-
-    for i := range move_table {
-        var entry string
-
-        // remove one-button, non-hyper commands
-        if !opt_keep1 {
-            if len(move_table[i].command) == 1 && move_table[i].power == 0 {continue}
-        }
-
-        cmd := detokenize(move_table[i].held) + detokenize(move_table[i].command)
-
-        entry = move_table[i].name
-
-        if move_table[i].power != 0 {
-            entry += " <#" + opt_color_power + ">(" + strconv.Itoa(move_table[i].power) + ")</>"
-            hypers_list += entry + "\t\t\t" + cmd + "\n"
-            continue
-        }
-
-        special_list += entry + "\t\t\t" + cmd + "\n"
+    for _, f := range failures {
+        fmt.Println(f.def + ": couldn't be checked,", describe_error(f.err))
     }
 
-    output := special_list
+    fmt.Println(len(problems), "problems found in", checked, "files.")
 
-    // checks if the hyper list has been populated at all
-    if (hypers_list != "<#" + opt_color_header + ">:Hyper Moves:</>\n") {
-        output += "\n" + hypers_list
+    if len(problems) > 0 || len(failures) > 0 {
+        os.Exit(1)
     }
-
-    return output
+    os.Exit(0)
 }
 
-func Convert(path string) string {
-    // wrapper function that does all of the actual work
-    // takes a file path as input and returns a movelist.dat as a string
-    // note the capital, we actually export this function for use by other programs (wink wink)
-
-    // loads the file, then parses it
-    if opt_debug {
-        fmt.Println("Reading input file...")
-    }
-    file_data, err := os.ReadFile(path)
-    check_error(err)
-
-    file_data = label_sctrl_with_comment(file_data)
-
-    if opt_debug {
-        fmt.Println("Parsing as INI data...")
+func describe_error(err error) string {
+    // Shortens errors from the conversion library down to something that fits on a single summary line
+
+    var not_found *iguana.NotFoundError
+    var read_err *iguana.ReadError
+    var parse_err *iguana.ParseError
+    var missing_cmd *iguana.MissingCmdError
+
+    switch {
+    case errors.As(err, &missing_cmd):
+        return "no cmd key in [Files]"
+    case errors.As(err, &not_found):
+        return "missing file " + not_found.Path
+    case errors.As(err, &read_err):
+        return "unreadable file " + read_err.Path
+    case errors.As(err, &parse_err):
+        if parse_err.Line > 0 {
+            return fmt.Sprintf("malformed file %s (line %d)", parse_err.Path, parse_err.Line)
+        }
+        return "malformed file " + parse_err.Path
     }
-    parsed_ini, err := ini.LoadSources(ini.LoadOptions{AllowNonUniqueSections: true, AllowShadows: true, SkipUnrecognizableLines: true}, file_data)
-    check_error(err)
-
-    // parse sections into dedicated structs
-    commands := scrape_commands(parsed_ini)
-    moves := scrape_moves(parsed_ini)
-
-    // combine the parsed data into a list of move names and command inputs
-    move_table := assemble_move_table(commands, moves)
 
-    // format the movelist we just made into the movelist.dat format
-    // (see https://github.com/ikemen-engine/Ikemen-GO/wiki/Miscellaneous-Info#movelists)
-    return format_move_table(move_table)
+    return err.Error()
 }
 
 func main() {
@@ -913,9 +441,10 @@ Distributed under the MIT license.
 `
         // Print cool logo and separation bars
         fmt.Printf(logo + "version " + version + "\n" + footer + hr + "\n")
+        fmt.Printf("\nUsage: iguana -i command.cmd [arguments], or iguana lint [arguments] folders/files... to check existing movelists\n")
         fmt.Printf("\nCommand arguments for IGUANA:\n")
 
-        flag_order := []string{"i", "o", "def", "", "keep1", "keepai", "kp", "nomotions", "header", "power", "", "d"}
+        flag_order := []string{"i", "o", "def", "backup", "dry-run", "merge", "", "keep1", "keepai", "kp", "nomotions", "split", "statetype", "header", "power", "powerstyle", "perbar", "barglyph", "charge", "release", "common", "lang", "text", "", "sources", "d"}
         for _, name := range flag_order {
             if name == "" {
                 fmt.Printf("\n")
@@ -930,16 +459,51 @@ Distributed under the MIT license.
         os.Exit(0)
     }
 
-    flag.StringVar(&input_file, "i", "", "command file to parse (required)")
+    flag.StringVar(&input_file, "i", "", "command file to parse (required), or a movelist.dat to rewrite with the options given")
     flag.StringVar(&output_file, "o", "movelist.dat", "output filename, excluding path")
     flag.BoolVar(&opt_debug, "d", false, "enables debug logging")
+    flag.BoolVar(&opt_sources, "sources", false, "also save a .sources.json mapping each movelist line to the sections it came from")
     flag.BoolVar(&opt_keep1, "keep1", false, "preserve one-button, non-hyper moves")
     flag.BoolVar(&opt_keepai, "keepai", false, "preserve move commands detected as AI-only")
     flag.BoolVar(&opt_nomotions, "nomotions", false, "don't compress directions to motion inputs")
+    flag.BoolVar(&opt_split, "split", false, "list alternative inputs for a move on separate lines")
+    flag.StringVar(&opt_statetypes, "statetype", "off", "mark air-only and ground-only moves: off, label or group")
     flag.BoolVar(&opt_usekp, "kp", false, "use LP/MP/HP/LK/MK/HK instead of A/B/C/X/Y/Z")
     flag.BoolVar(&opt_patchdef, "def", false, "automatically patches .def files when used as input")
+    flag.BoolVar(&opt_backup, "backup", false, "save a .bak of each .def before patching it, keeping any that already exists")
+    flag.BoolVar(&opt_dry_run, "dry-run", false, "print a diff of every file that would change, without changing any of them")
+    flag.BoolVar(&opt_merge, "merge", false, "keep the entries of an existing movelist, only adding moves it doesn't have yet")
     flag.StringVar(&opt_color_header, "header", "f0f000", "hex-color (without #) to use for headers")
     flag.StringVar(&opt_color_power, "power", "bebebe", "hex-color (without #) to use for move power usage")
+    flag.StringVar(&opt_power_style, "powerstyle", "numbers", "how move power usage is shown: numbers, levels or bars")
+    flag.IntVar(&opt_per_bar, "perbar", 1000, "amount of power in one bar, used by -powerstyle levels and bars")
+    flag.StringVar(&opt_bar_glyph, "barglyph", "*", "text repeated for each bar, used by -powerstyle bars")
+    flag.StringVar(&opt_charge_format, "charge", "", "format of charge inputs; {dir} is the direction or button, {time} the hold duration (default \"[{dir}] charge, \", translated)")
+    flag.StringVar(&opt_release_format, "release", "", "format of released buttons; {button} is the button (default \"{button}(release)\", translated)")
+    flag.StringVar(&opt_lang, "lang", "", "language of the .def files to use (e.g. ja), or \"all\" for one movelist per language")
+    flag.StringVar(&opt_text, "text", "", "language of headers and labels (" + strings.Join(iguana.Languages(), ", ") + ") or a translation file; defaults to -lang")
+    flag.StringVar(&opt_common, "common", "", "Ikemen GO common.cmd (or Ikemen GO folder) to use; found automatically if not given, \"none\" to skip")
+
+    // lint is a mode of its own, taking paths instead of -i
+    if len(os.Args) > 1 && os.Args[1] == "lint" {
+        // flags can come before, between or after the paths, so parsing picks back up after each path it stops at
+        var paths []string
+        args := os.Args[2:]
+
+        for {
+            flag.CommandLine.Parse(args)
+            if flag.NArg() == 0 {break}
+
+            paths = append(paths, flag.Arg(0))
+            args = flag.Args()[1:]
+        }
+
+        if len(paths) == 0 {
+            fmt.Printf("No files given to check. Syntax is 'iguana lint chars/'\n")
+            os.Exit(0)
+        }
+        lint(paths)
+    }
 
     flag.Parse()
 
@@ -966,9 +530,12 @@ It will attempt to process every command file specified by every definition (.de
 Making a backup of this folder is recommended before continuing.
 Are you sure you want to continue? `
 
-        fmt.Printf(prompt_msg)
+        // nothing gets written in a dry run, so there's no need to warn about it
+        if !opt_dry_run {
+            fmt.Printf(prompt_msg)
+        }
 
-        if prompt() {
+        if opt_dry_run || prompt() {
             prompt_msg = `
 Iguana can also automatically patch .def files it processes to use the movelist.
 Would you like to enable this too? `
@@ -987,43 +554,47 @@ Would you like to enable this too? `
                 return nil
             })
 
-            fmt.Println("Found", len(def_file_list), ".def files to convert.\n")
+            fmt.Println("Found", len(def_file_list), ".def files to convert.")
+            fmt.Println("")
+
+            // a broken character shouldn't stop the rest of the roster from being converted,
+            // so failures get collected here and summarized once everything's done
+            var failures []failure
 
             for d := range def_file_list {
                 fmt.Println("Reading:", def_file_list[d])
-                f := get_cmd_from_def(def_file_list[d])
 
-                fmt.Println("Converting file: " + f)
-                movelist := Convert(f)
+                err := convert_def(def_file_list[d])
+                if err != nil {
+                    fmt.Println("Error:", err)
+                    failures = append(failures, failure{def_file_list[d], err})
+                }
+                fmt.Println("")
+            }
 
-                if opt_debug {
-                    fmt.Println("Dump of movelist:\n" + movelist)
-                } else {
-                    path := filepath.Dir(f) + "/" + output_file
-                    err := os.WriteFile(path, []byte(movelist), 0666)
-                    check_error(err)
+            if len(failures) > 0 {
+                fmt.Println(hr)
+                fmt.Println(len(failures), "of", len(def_file_list), "characters couldn't be converted:")
 
-                    if (opt_patchdef) {
-                        patch_def(def_file_list[d])
-                    }
+                for _, f := range failures {
+                    fmt.Println(" -", f.def + ":", describe_error(f.err))
                 }
-                fmt.Println("")
+
+                os.Exit(1)
             }
         } else {
             os.Exit(0)
         }
 
+    } else if filepath.Ext(input_file) == ".dat" {
+        // an existing movelist doesn't need a command file to be written out again
+        check_error(convert_movelist(input_file))
     } else {
         var def_file string = ""
+        var languages = []string{""}
         if filepath.Ext(input_file) == ".def" {
             def_file = input_file // save DEF path for later
-            input_file = get_cmd_from_def(input_file)
-        }
-
-        // ask for confirmation if the input file isn't a directly-supported extension
-        if filepath.Ext(input_file) != ".cmd" {
-            fmt.Printf("This file doesn't seem to be a command (.cmd) file. Process anyways? ")
-            if !prompt() {os.Exit(0)}
+            languages = def_languages(def_file)
         }
 
         // make a note if debug logging is on
@@ -1031,23 +602,56 @@ Would you like to enable this too? `
             fmt.Println("Debug logging enabled.")
         }
 
-        // at this point, we know we have a file, so try to do stuff with it
-        movelist := Convert(input_file)
+        // only ask about patching once, even if there's several languages to patch
+        var ask_patch = true
 
-        if opt_debug {
-            fmt.Println("Dump of movelist:\n" + movelist)
-        } else {
-            path := filepath.Dir(input_file) + "/" + output_file
-            fmt.Println("Saving to path: " + path)
-            err := os.WriteFile(path, []byte(movelist), 0666)
+        // a .def can give files for several languages, each of which gets a movelist of its own
+        for _, lang := range languages {
+            var char = iguana.Character{Cmd: input_file}
+            if def_file != "" {
+                char, err = iguana.LoadDefLanguage(def_file, lang)
+                check_error(err)
+            } else {
+                char.Common = iguana.FindCommonCommands(filepath.Dir(input_file))
+            }
+            common_commands(&char)
+
+            // ask for confirmation if the input file isn't a directly-supported extension
+            if filepath.Ext(char.Cmd) != ".cmd" {
+                fmt.Printf("This file doesn't seem to be a command (.cmd) file. Process anyways? ")
+                if !prompt() {os.Exit(0)}
+            }
+
+            // at this point, we know we have a file, so try to do stuff with it
+            movelist, sources, err := iguana.ConvertCharacterWithSources(char, options())
+            check_error(err)
+
+            if opt_debug {
+                fmt.Println("Dump of movelist:\n" + movelist)
+                continue
+            }
+
+            path := filepath.Dir(char.Cmd) + "/" + localized_output(lang)
+            if !opt_dry_run {
+                fmt.Println("Saving to path: " + path)
+            }
+            movelist, sources, err = merge_existing(path, movelist, sources)
             check_error(err)
+            err = write_output(path, []byte(movelist))
+            check_error(err)
+            check_error(write_sources(path, sources))
 
             if def_file != "" {
                 if (opt_patchdef) {
-                    patch_def(def_file)
-                } else {
+                    check_error(patch_def(def_file, lang))
+                } else if ask_patch {
                     fmt.Printf("Would you like to also patch the .def file to use your movelist? ")
-                    if prompt() {patch_def(def_file)} else {os.Exit(0)}
+                    if prompt() {
+                        opt_patchdef = true
+                        check_error(patch_def(def_file, lang))
+                    } else {
+                        ask_patch = false
+                    }
                 }
             }
         }
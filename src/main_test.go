@@ -0,0 +1,46 @@
+package main
+
+import (
+    "os"
+    "path/filepath"
+    "strings"
+    "testing"
+    "github.com/superfromnd/iguana"
+)
+
+func TestMergeExistingSources(t *testing.T) {
+    // the sources of lines that got moved by the merge should follow them, and the ones whose lines were left out should be dropped
+    opt_merge = true
+    defer func() {opt_merge = false}()
+
+    existing := "<#f0f000>:Special Moves:</>\n" +
+        "Kick\t\t\t_B^B<#000000></>\n" +
+        "Fireball (my notes)\t\t\t_QCF^A\n"
+    generated := "<#f0f000>:Special Moves:</>\n" +
+        "Fireball\t\t\t_QCF^A\n" +
+        "Kick\t\t\t_QCB^B\n"
+    sources := []iguana.LineSource{
+        {Line: 2, Text: "Fireball\t\t\t_QCF^A", Move: iguana.Source{Section: "State -1, Fireball"}},
+        {Line: 3, Text: "Kick\t\t\t_QCB^B", Move: iguana.Source{Section: "State -1, Kick"}},
+    }
+
+    path := filepath.Join(t.TempDir(), "movelist.dat")
+    if err := os.WriteFile(path, []byte(existing), 0644); err != nil {
+        t.Fatal(err)
+    }
+
+    merged, merged_sources, err := merge_existing(path, generated, sources)
+    if err != nil {
+        t.Fatal(err)
+    }
+
+    // the hand-written fireball is kept over the generated one, so only the kick has somewhere to point
+    if len(merged_sources) != 1 || merged_sources[0].Move.Section != "State -1, Kick" {
+        t.Fatalf("got sources %+v, want only the kick's", merged_sources)
+    }
+
+    lines := strings.Split(merged, "\n")
+    if line := merged_sources[0].Line; line != 2 || lines[line-1] != "Kick\t\t\t_QCB^B<#000000></>" {
+        t.Errorf("the kick's source points at line %d of:\n%s", line, merged)
+    }
+}
@@ -0,0 +1,129 @@
+package iguana
+
+import (
+    "path/filepath"
+    "strconv"
+    "strings"
+)
+
+func (cv *converter) scrape_constants(path string) {
+    // Reads the character's max power from the [Data] section of its constants file
+    // this is what the powermax and const(data.power) triggers are, so it's stored alongside them
+
+    if path == "" {
+        return
+    }
+
+    file_data, err := read_file(path)
+    if err != nil {
+        cv.debug("Couldn't read constants file:", err)
+        return
+    }
+
+    for _, section := range parse_mugen_ini(path, file_data).sections {
+        if !section.is("Data") {continue}
+
+        power_max, ok := parse_constant(section.value("power"))
+        if ok && power_max > 0 {
+            cv.debug("Found max power:", power_max)
+            cv.known["powermax"] = power_max
+            cv.known["const(data.power)"] = power_max
+        }
+        return
+    }
+}
+
+func (cv *converter) load_state_files(files []string, cmd string) []*ini_document {
+    // Reads and parses every given state file, skipping the command file if it's also listed as one
+
+    var parsed []*ini_document
+
+    for _, path := range files {
+        if filepath.Clean(path) == filepath.Clean(cmd) {continue}
+
+        // ZSS files aren't INI data at all, so they're read separately (see zss.go)
+        if is_zss(path) {continue}
+
+        // a missing state file shouldn't stop the movelist from being made, it'll just be missing whatever was in it
+        file_data, err := read_file(path)
+        if err != nil {
+            cv.debug("Couldn't read state file:", err)
+            continue
+        }
+
+        cv.debug("Loaded state file", path)
+        parsed = append(parsed, parse_mugen_ini(path, file_data))
+    }
+
+    return parsed
+}
+
+func (cv *converter) scrape_state_moves(files []*ini_document) []Move {
+    // Returns the moves from any [Statedef -1] found in the given state files
+    // MUGEN and Ikemen GO both merge these with the command file's own [Statedef -1], so plenty of characters keep their moves here instead
+
+    var moves []Move
+
+    for _, parsed_ini := range files {
+        moves = append(moves, cv.scrape_moves(parsed_ini)...)
+    }
+
+    return moves
+}
+
+func (cv *converter) scrape_state_costs(files []*ini_document) map[int]int {
+    // Returns how much power each state in the given files spends, keyed by state number
+    // this is found from either the poweradd parameter of a [Statedef], or the PowerAdd controllers inside of it
+
+    costs := make(map[int]int)
+
+    for _, parsed_ini := range files {
+        cv.debug("Scraping power costs...", "\n"+hr)
+        cv.scrape_state_file_costs(parsed_ini, costs)
+    }
+
+    return costs
+}
+
+func (cv *converter) scrape_state_file_costs(input *ini_document, costs map[int]int) {
+    // Adds the power costs of every state in a single state file to the given map
+
+    var statedef int
+    var in_statedef = false
+
+    for _, section := range input.sections {
+        var sect_name = strings.ToLower(section.name)
+
+        // controllers belong to whichever [Statedef] came before them, regardless of the number in their own name
+        if strings.HasPrefix(sect_name, "statedef ") {
+            number, err := strconv.Atoi(strings.TrimSpace(sect_name[len("statedef "):]))
+            in_statedef = err == nil
+            statedef = number
+
+            if in_statedef {
+                record_cost(costs, statedef, section.value("poweradd"))
+            }
+            continue
+        }
+
+        if !in_statedef || !strings.HasPrefix(sect_name, "state ") {continue}
+
+        if strings.EqualFold(section.value("type"), "PowerAdd") {
+            record_cost(costs, statedef, section.value("value"))
+        }
+    }
+}
+
+func record_cost(costs map[int]int, state int, value string) {
+    // Records a power change as the cost of a state, if it's actually spending power and is the most expensive one so far
+    // states with several PowerAdds are usually picking between them, so the most expensive one is used rather than the total
+
+    amount, ok := parse_constant(value)
+    if !ok || amount >= 0 {
+        return
+    }
+
+    if int(-amount) > costs[state] {
+        costs[state] = int(-amount)
+    }
+}
@@ -0,0 +1,485 @@
+package iguana
+
+import (
+    "fmt"
+    "regexp"
+    "strconv"
+    "strings"
+)
+
+func reverse(input string) string {
+    // Flips the given string around
+
+    var output string
+
+    for _, i := range input {
+        output = string(i) + output
+    }
+
+    return output
+}
+
+func remove_element(input []string, index int) []string {
+    // Returns an array minus the given element
+    j := 0
+
+    for i := range input {
+        if i != index {
+            input[j] = input[i]
+            j++
+        }
+    }
+
+    output := input[:j]
+    return output
+}
+
+func validate_hex_color(output string) string {
+    // Checks if the given string is a valid hex string
+    // The returned string should be A. either three or six characters long and B. consist only of hexadecimal (0-F)
+
+    // this regex matches any string containing lowercase hex digits AND only in pairs of 3 or 6
+    hexcolor_regex, _ := regexp.Compile("^([0-9a-f]{3}){1,2}$")
+
+    if hexcolor_regex.MatchString(strings.ToLower(output)) {
+        return output
+    } else {
+        // return magenta if the color is invalid hex
+        return "ff00ff"
+    }
+}
+
+func (cv *converter) command_inputs(name string, commands []Command) ([]string, bool) {
+    // Returns the tokenized inputs of every [Command] with the given name, without duplicates
+    // MUGEN allows several commands to share a name, in which case any of them can be used
+    // the returned bool is false if the name was found, but only on commands meant for the AI
+
+    var inputs []string
+    found := false
+
+    for c := range commands {
+        // checks if the current command has a corresponding [Command] entry
+        if name == commands[c].name {
+            found = true
+
+            if (!cv.opt.KeepAI && cv.detect_ai_command(commands[c])) {
+                cv.debug("Command detected as AI-only:", name)
+                continue
+            }
+
+            cv.debug("Tokenizing string:", commands[c].command)
+
+            command_text := cv.tokenize(commands[c].command)
+
+            cv.debug("Tokenized:", command_text)
+            if !contains_string(inputs, command_text) {
+                inputs = append(inputs, command_text)
+            }
+        }
+    }
+
+    // a name that exists but only has AI commands makes the whole alternative unusable
+    return inputs, !found || len(inputs) > 0
+}
+
+func (cv *converter) combine_inputs(inputs []string) (Variant, bool) {
+    // Combines the inputs of commands that all have to be done at once (e.g. command = "holdfwd" && command = "x") into one variant
+    // returns false if there's nothing usable in the given inputs
+
+    var v Variant
+
+    // separate the held-input tokens from the regular tokens
+    // held inputs are traditionally kept at the beginning of a command, so we put them into an array to read off later
+    var holding []string
+    var nonholding []string
+    token_regex, _ := regexp.Compile("[!@#$%^&*()[\\];'.~>]")
+    button_regex, _ := regexp.Compile("^[abcxyzsdw]$")
+
+    for e := range inputs {
+        if len(inputs[e]) == 1 && token_regex.MatchString(inputs[e][0:1]) {
+            holding = append(holding, inputs[e])
+        } else {
+            nonholding = append(nonholding, inputs[e])
+        }
+    }
+
+    // buttons are split from the rest, as they're pressed together after everything else is done
+    var motions []string
+    var buttons []string
+
+    for e := range nonholding {
+        if button_regex.MatchString(nonholding[e]) {
+            buttons = append(buttons, nonholding[e])
+        } else {
+            motions = append(motions, nonholding[e])
+        }
+    }
+
+    if len(nonholding) == 0 {
+        // do nothing
+    } else if len(nonholding) == 1 {
+        // there's only one command, so just use it verbatim
+        v.command = nonholding[0]
+    } else if len(nonholding) == 2 && nonholding[0] == reverse(nonholding[1]) {
+        // heuristic: if there's only two non-held commands that are identical flipped, return one of them
+        // this specifically is meant to catch KFM's blocking inputs among other similar commands
+        cv.debug("Commands detected to be identical when mirrored. Returning one of them...")
+        v.command = nonholding[0]
+    } else {
+        // every command has to be done, so they're written out in order: motions first, then the buttons pressed together
+        // (e.g. command = "QCF" && command = "x" becomes QCF, x)
+        v.command = strings.Join(motions, "") + strings.Join(buttons, "+")
+    }
+
+    if holding != nil {
+        cv.debug("Held inputs found:", holding)
+        v.held = fmt.Sprintf("%s", holding)
+    }
+
+    return v, v.command != "" || holding != nil
+}
+
+func (cv *converter) assemble_move_table(commands []Command, moves []Move, state_costs map[int]int) []MoveEntry {
+    // Takes commands and moves, then converts them to entries in an array
+    // The returned array is then formatted by format_move_table() and saved to disk
+
+    cv.debug("Assembling move table...", "\n"+hr)
+    var movelist []MoveEntry
+    var sources []Move
+
+    for m := range moves {
+        var mv MoveEntry
+
+        mv.name = moves[m].name
+        mv.statetypes = moves[m].statetypes
+        mv.state = moves[m].value
+        mv.source = moves[m].source
+        cv.debug("Reading move:", mv.name)
+
+        // every alternative of the move's triggers becomes its own variant (or several, if its commands share names)
+        for _, alt := range moves[m].commands {
+            combinations := [][]string{{}}
+            usable := true
+
+            for _, name := range alt {
+                inputs, ok := cv.command_inputs(name, commands)
+                if !ok {
+                    usable = false
+                    break
+                }
+
+                // names that don't match any [Command] are skipped, rather than throwing out the whole alternative
+                if len(inputs) == 0 {continue}
+
+                for _, c := range commands {
+                    if c.name == name && !contains_source(mv.commands, c.source) {
+                        mv.commands = append(mv.commands, c.source)
+                    }
+                }
+
+                var expanded [][]string
+                for _, combination := range combinations {
+                    for _, input := range inputs {
+                        if len(expanded) < max_alternatives {
+                            expanded = append(expanded, append(append([]string{}, combination...), input))
+                        }
+                    }
+                }
+                combinations = expanded
+            }
+
+            if !usable {continue}
+
+            for _, combination := range combinations {
+                v, ok := cv.combine_inputs(combination)
+
+                if ok && !contains_variant(mv.variants, v) {
+                    mv.variants = append(mv.variants, v)
+                }
+            }
+        }
+
+        // same heuristic as in combine_inputs(), but for alternatives (e.g. command = "F,B" || command = "B,F")
+        if len(mv.variants) == 2 && mv.variants[0].held == mv.variants[1].held && mv.variants[0].command == reverse(mv.variants[1].command) {
+            cv.debug("Variants detected to be identical when mirrored. Returning one of them...")
+            mv.variants = mv.variants[:1]
+        }
+
+        mv.power = moves[m].power
+
+        // a lot of characters don't check power in their triggers at all, and only spend it once they're in the move's state
+        if state, err := strconv.Atoi(mv.state); err == nil && state_costs[state] > mv.power {
+            cv.debug("Power cost found in state", state, "of", state_costs[state])
+            mv.power = state_costs[state]
+        }
+
+        // fallback if, for whatever reason, we end up with no inputs at all
+        if len(mv.variants) == 0 {
+            cv.debug("Move", mv.name, "has no usable commands. Discarding...")
+            continue
+        }
+
+        movelist = append(movelist, mv)
+        sources = append(sources, moves[m])
+    }
+
+    return cv.link_follow_ups(movelist, sources)
+}
+
+func (cv *converter) link_follow_ups(movelist []MoveEntry, sources []Move) []MoveEntry {
+    // Finds moves that can only be done from the state another move leads to (e.g. the second and third hits of a rekka)
+    // and reorders the list so that each of them comes right after the move it follows up, one level deeper
+
+    parents := make([]int, len(movelist))
+
+    for i := range movelist {
+        parents[i] = -1
+
+        for j := range movelist {
+            if i == j || movelist[j].state == "" || is_ancestor(parents, i, j) {continue}
+
+            if sources[i].gated_by(movelist[j].state) {
+                cv.debug("Move", movelist[i].name, "detected as a follow-up to", movelist[j].name)
+                parents[i] = j
+                break
+            }
+        }
+    }
+
+    var output []MoveEntry
+    var add func(index int, depth int)
+
+    add = func(index int, depth int) {
+        mv := movelist[index]
+        mv.depth = depth
+        output = append(output, mv)
+
+        for i := range movelist {
+            if parents[i] == index {
+                add(i, depth + 1)
+            }
+        }
+    }
+
+    for i := range movelist {
+        if parents[i] == -1 {
+            add(i, 0)
+        }
+    }
+
+    return output
+}
+
+func is_ancestor(parents []int, ancestor int, index int) bool {
+    // Checks if linking the move at index to a parent would make it a follow-up of itself
+
+    for index != -1 {
+        if index == ancestor {
+            return true
+        }
+        index = parents[index]
+    }
+
+    return false
+}
+
+func contains_source(input []Source, value Source) bool {
+    for _, i := range input {
+        if i == value {
+            return true
+        }
+    }
+    return false
+}
+
+func contains_variant(input []Variant, value Variant) bool {
+    for _, i := range input {
+        if i == value {
+            return true
+        }
+    }
+    return false
+}
+
+func (cv *converter) format_power(power int) string {
+    // Formats a move's power usage following the PowerStyle option
+
+    if cv.opt.PowerStyle == PowerStyleNumbers {
+        return strconv.Itoa(power)
+    }
+
+    // anything short of a full bar still needs that bar to be (partially) filled, so round up
+    bars := (power + cv.opt.PowerPerBar - 1) / cv.opt.PowerPerBar
+
+    if power_max, ok := cv.known["powermax"]; ok {
+        if max_bars := int(power_max) / cv.opt.PowerPerBar; max_bars > 0 && bars > max_bars {
+            bars = max_bars
+        }
+    }
+
+    if cv.opt.PowerStyle == PowerStyleBars {
+        return strings.Repeat(cv.opt.BarGlyph, bars)
+    }
+
+    return cv.translate("level") + strconv.Itoa(bars)
+}
+
+func (cv *converter) format_move_table(move_table []MoveEntry) (string, []LineSource) {
+    // Takes a given array of moves and formats it into movelist.dat's formatting
+    // What this function returns is then saved to disk, along with where each of its lines came from
+
+    cv.debug("Formatting move table...", "\n"+hr)
+
+    color_header := validate_hex_color(cv.opt.HeaderColor)
+    color_power := validate_hex_color(cv.opt.PowerColor)
+
+    special_header := "<#" + color_header + ">:" + cv.translate("special") + ":</>\n"
+    air_header := "<#" + color_header + ">:" + cv.translate("air") + ":</>\n"
+    hypers_header := "<#" + color_header + ">:" + cv.translate("hyper") + ":</>\n"
+
+    special_list := special_header
+    air_list := air_header
+    hypers_list := hypers_header
+
+    // the list each move ended up in (nil if it was left out), so that follow-ups can be put in the same one
+    sections := make([]*string, len(move_table))
+    depths := make([]int, len(move_table))
+    var last_at_depth []int
+
+    // where each line of each list came from, in the same order as the lines themselves
+    section_sources := make(map[*string][]LineSource)
+
+    // moves read from an existing movelist keep the headers they were under, in the order they were first found
+    header_lists := make(map[string]*string)
+    var header_order []*string
+
+    for i := range move_table {
+        var entry string
+        var variants []string
+
+        // follow-ups come right after the move they follow up, so the parent is the last move found one level up
+        // (a move deeper than anything before it, like one indented too far in a hand-written movelist, follows up the deepest one there is)
+        level := move_table[i].depth
+        if level > len(last_at_depth) {
+            level = len(last_at_depth)
+        }
+
+        parent := -1
+        if level > 0 {
+            parent = last_at_depth[level - 1]
+        }
+        last_at_depth = append(last_at_depth[:level], i)
+
+        for _, v := range move_table[i].variants {
+            // remove one-button, non-hyper commands
+            if !cv.opt.KeepOneButton && !move_table[i].verbatim {
+                if len(v.command) == 1 && move_table[i].power == 0 {continue}
+            }
+
+            variants = append(variants, cv.detokenize(v.held) + cv.detokenize(v.command))
+        }
+
+        // (lines from an existing movelist with no inputs at all, like notes, are still kept)
+        if len(variants) == 0 && !move_table[i].verbatim {continue}
+
+        // follow-ups are indented under their parent, as long as it made it into the movelist
+        // lines from an existing movelist keep the indentation they were written with, though
+        if move_table[i].verbatim {
+            depths[i] = move_table[i].depth
+        } else if parent != -1 && sections[parent] != nil {
+            depths[i] = depths[parent] + 1
+        }
+
+        entry = strings.Repeat("    ", depths[i]) + move_table[i].name
+        state_label := statetype_label(move_table[i].statetypes)
+
+        // grouped air moves already have a header saying as much, so they don't need the label (hypers still do, though)
+        is_grouped := cv.opt.StateTypes == StateTypesGroup && state_label == "Air" && move_table[i].power == 0
+
+        if (cv.opt.StateTypes == StateTypesLabel && state_label != "") || (cv.opt.StateTypes == StateTypesGroup && state_label == "Air" && !is_grouped) {
+            entry += " (" + cv.translate(statetype_label_keys[state_label]) + ")"
+        }
+
+        if move_table[i].power != 0 {
+            entry += " <#" + color_power + ">(" + cv.format_power(move_table[i].power) + ")</>"
+        }
+
+        // alternatives are either listed on lines of their own, or all on one line
+        var lines string
+        if len(variants) == 0 {
+            lines = entry + "\n"
+        } else if cv.opt.SplitVariants {
+            for _, cmd := range variants {
+                lines += entry + "\t\t\t" + cmd + "\n"
+            }
+        } else {
+            lines = entry + "\t\t\t" + strings.Join(variants, " " + cv.translate("or") + " ") + "\n"
+        }
+
+        switch {
+        case depths[i] > 0 && parent != -1:
+            sections[i] = sections[parent]
+        case move_table[i].header != "":
+            if header_lists[move_table[i].header] == nil {
+                list := "<#" + color_header + ">:" + move_table[i].header + ":</>\n"
+                header_lists[move_table[i].header] = &list
+                header_order = append(header_order, &list)
+            }
+            sections[i] = header_lists[move_table[i].header]
+        case move_table[i].power != 0:
+            sections[i] = &hypers_list
+        case is_grouped:
+            sections[i] = &air_list
+        default:
+            sections[i] = &special_list
+        }
+
+        *sections[i] += lines
+
+        for _, line := range strings.Split(strings.TrimSuffix(lines, "\n"), "\n") {
+            section_sources[sections[i]] = append(section_sources[sections[i]], LineSource{Text: line, Move: move_table[i].source, Commands: move_table[i].commands})
+        }
+    }
+
+    var output string
+    var sources []LineSource
+    var line_number int
+
+    add_list := func(list *string) {
+        // lists after the first are separated by a blank line
+        if output != "" {
+            output += "\n"
+            line_number++
+        }
+
+        output += *list
+        line_number++ // (for the header)
+
+        for _, source := range section_sources[list] {
+            line_number++
+            source.Line = line_number
+            sources = append(sources, source)
+        }
+    }
+
+    // the special moves header is always there, unless the moves all have headers of their own
+    if special_list != special_header || len(header_order) == 0 {
+        add_list(&special_list)
+    }
+
+    // checks if the air move list has been populated at all
+    if (air_list != air_header) {
+        add_list(&air_list)
+    }
+
+    // checks if the hyper list has been populated at all
+    if (hypers_list != hypers_header) {
+        add_list(&hypers_list)
+    }
+
+    for _, list := range header_order {
+        add_list(list)
+    }
+
+    return output, sources
+}
@@ -0,0 +1,71 @@
+package iguana
+
+import (
+    "os"
+    "path/filepath"
+    "strings"
+    "testing"
+)
+
+// commands shared by every move in the combine_inputs tests
+const combine_commands = `
+[Command]
+name = "QCF"
+command = D, DF, F
+
+[Command]
+name = "QCB"
+command = D, DB, B
+
+[Command]
+name = "x"
+command = x
+
+[Command]
+name = "y"
+command = y
+
+[Command]
+name = "holdfwd"
+command = /F
+
+[Statedef -1]
+`
+
+func TestCombineInputs(t *testing.T) {
+    tests := []struct {
+        name     string
+        triggers string
+        want     string
+    }{
+        {"motion and button", `triggerall = command = "QCF" && command = "x"`, "_QCF^X"},
+        {"split motion and button", "triggerall = command = \"QCF\"\ntriggerall = command = \"x\"", "_QCF^X"},
+        {"button before motion", `triggerall = command = "x" && command = "QCF"`, "_QCF^X"},
+        {"two buttons", `triggerall = command = "x" && command = "y"`, "^X_+^Y"},
+        {"two motions", `triggerall = command = "QCF" && command = "QCB" && command = "x"`, "_QCF_QCB^X"},
+        {"held direction", `triggerall = command = "holdfwd" && command = "x"`, "[~F]^X"},
+    }
+
+    for _, test := range tests {
+        t.Run(test.name, func(t *testing.T) {
+            path := filepath.Join(t.TempDir(), "test.cmd")
+            cmd := combine_commands + "[State -1, Test Move]\ntype = ChangeState\n" + test.triggers + "\n"
+
+            if err := os.WriteFile(path, []byte(cmd), 0644); err != nil {
+                t.Fatal(err)
+            }
+
+            opt := DefaultOptions()
+            opt.KeepOneButton = true
+            movelist, err := Convert(path, opt)
+            if err != nil {
+                t.Fatal(err)
+            }
+
+            want := "Test Move\t\t\t" + test.want + "\n"
+            if !strings.Contains(movelist, want) {
+                t.Errorf("got movelist:\n%s\nwant a line %q", movelist, want)
+            }
+        })
+    }
+}
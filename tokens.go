@@ -0,0 +1,335 @@
+package iguana
+
+import (
+    "regexp"
+    "strconv"
+    "strings"
+)
+
+// matches the charge tokens created by tokenize(), e.g. "|430|" is back (4) held for 30 ticks, and "|a30|" is a held for 30 ticks
+// unlike every other token these are more than one character long, since they also have to carry the charge duration
+var charge_regex = regexp.MustCompile(`\|([1-9abcxyzsdw])([0-9]+)\|`)
+
+// matches literal tokens, which hold text from an existing movelist that isn't a glyph (e.g. "`(x3)`")
+// they're shown exactly as they're written, minus the backticks
+var literal_regex = regexp.MustCompile("`[^`]*`")
+
+// the numpad notation digit for each direction that can be charged
+var charge_directions = map[string]string{
+    "DB": "1", "D": "2", "DF": "3", "B": "4",
+    "F":  "6", "UB": "7", "U": "8", "UF": "9",
+}
+
+func tokenize_charge(input string) string {
+    // Converts charge inputs (a ~ followed by a hold duration and a direction, e.g. ~30B) into charge tokens
+    // a ~ without a duration (or in front of a button) is a release, which is handled by tokenize() instead
+
+    charge_input_regex, _ := regexp.Compile(`^>?~([0-9]+)\$?(DF|DB|UF|UB|D|F|U|B)$`)
+    split_strings := strings.Split(input, ",")
+
+    for i := range split_strings {
+        match := charge_input_regex.FindStringSubmatch(split_strings[i])
+
+        if match != nil {
+            duration, _ := strconv.Atoi(match[1])
+            if duration > 0 {
+                split_strings[i] = "|" + charge_directions[match[2]] + strconv.Itoa(duration) + "|"
+            }
+        }
+    }
+
+    return strings.Join(split_strings, ",")
+}
+
+func map_outside_charges(input string, fn func(string) string, charge_fn func(string) string) string {
+    // Runs fn over every part of the given token string that isn't a charge token, and charge_fn over the ones that are
+    // this keeps the digits of a charge's duration from being mistaken for directions
+
+    return map_outside(charge_regex, input, fn, charge_fn)
+}
+
+func map_outside(regex *regexp.Regexp, input string, fn func(string) string, match_fn func(string) string) string {
+    // Runs fn over every part of the given string that the regex doesn't match, and match_fn over the parts it does
+
+    var output string
+    last := 0
+
+    for _, loc := range regex.FindAllStringIndex(input, -1) {
+        output += fn(input[last:loc[0]]) + match_fn(input[loc[0]:loc[1]])
+        last = loc[1]
+    }
+
+    return output + fn(input[last:])
+}
+
+func (cv *converter) tokenize(input string) string {
+    // Tokenizes the command string, replacing each multi-char button input with a single character
+    // this is done so that merging them becomes easier
+
+    // charge inputs get their own token, which has to happen before the rest of the ~ notation is handled below
+    input = tokenize_charge(input)
+
+    // released buttons are marked with a - in front of the button (e.g. ~a becomes -a)
+    // a button that has to be held for a while first also gets a charge token for the hold (e.g. ~30a becomes |a30|-a)
+    release_regex, _ := regexp.Compile("~([0-9]*)([abcxyzsdw])")
+    input = release_regex.ReplaceAllStringFunc(input, func(release string) string {
+        match := release_regex.FindStringSubmatch(release)
+        duration, _ := strconv.Atoi(match[1])
+
+        if duration > 0 {
+            return "|" + match[2] + strconv.Itoa(duration) + "|-" + match[2]
+        }
+        return "-" + match[2]
+    })
+
+    // released directions (like the ~D at the start of KFM's QCF) act just like regular presses for our purposes, so strip those
+    release_dir_regex, _ := regexp.Compile("~([0-9]*)")
+    input = release_dir_regex.ReplaceAllString(input, "")
+
+    // > indicates to not press any button betwen previous and next command, unnecessary for us so strip it
+    input = strings.ReplaceAll(input, ">", "")
+
+    // $ indicates to read a direction as 4-way, unnecessary for us so strip it
+    input = strings.ReplaceAll(input, "$", "")
+
+    var split_strings []string = strings.Split(input, ",")
+    var output string
+
+    // split up button inputs strings and concatenate them into a new string
+    // this is so we can use more ASCII characters for tokens without causing conflicts
+    for i := range split_strings {
+        str := split_strings[i]
+
+        // tokenize any held inputs into single characters
+        // note the specific characters we're using for tokenizing here are ASCII-compliant
+        str = strings.ReplaceAll(str, "/DF", "!")
+        str = strings.ReplaceAll(str, "/DB", "@")
+        str = strings.ReplaceAll(str, "/UF", "#")
+        str = strings.ReplaceAll(str, "/UB", "$")
+        str = strings.ReplaceAll(str, "/D", "%")
+        str = strings.ReplaceAll(str, "/F", "^")
+        str = strings.ReplaceAll(str, "/U", "&")
+        str = strings.ReplaceAll(str, "/B", "*")
+        str = strings.ReplaceAll(str, "/a", "(")
+        str = strings.ReplaceAll(str, "/b", ")")
+        str = strings.ReplaceAll(str, "/c", "<")
+        str = strings.ReplaceAll(str, "/x", ">")
+        str = strings.ReplaceAll(str, "/y", ";")
+        str = strings.ReplaceAll(str, "/z", "'")
+        str = strings.ReplaceAll(str, "/s", "{")
+        str = strings.ReplaceAll(str, "/d", "?")
+        str = strings.ReplaceAll(str, "/w", "=")
+
+        // tokenize regular directional inputs
+        str = strings.ReplaceAll(str, "DF", "3")
+        str = strings.ReplaceAll(str, "DB", "1")
+        str = strings.ReplaceAll(str, "UF", "9")
+        str = strings.ReplaceAll(str, "UB", "7")
+        str = strings.ReplaceAll(str, "D", "2")
+        str = strings.ReplaceAll(str, "F", "6")
+        str = strings.ReplaceAll(str, "U", "8")
+        str = strings.ReplaceAll(str, "B", "4")
+
+        output += str
+    }
+
+    if !cv.opt.NoMotions {
+        output = map_outside_charges(output, compress_motions, func(token string) string {return token})
+    }
+
+    return output
+}
+
+func compress_motions(output string) string {
+    // Replaces sequences of direction tokens with single-character motion input tokens
+
+    // full circles
+    output = strings.ReplaceAll(output, "21478963", "v")
+    output = strings.ReplaceAll(output, "23698741", "n")
+    output = strings.ReplaceAll(output, "89632147", "V")
+    output = strings.ReplaceAll(output, "87412369", "N")
+
+    // full circles (simplified)
+    output = strings.ReplaceAll(output, "2486", "v")
+    output = strings.ReplaceAll(output, "2684", "n")
+    output = strings.ReplaceAll(output, "8624", "V")
+    output = strings.ReplaceAll(output, "8426", "N")
+
+    // full circles (truncated variations)
+    output = strings.ReplaceAll(output, "6248", "V")
+    output = strings.ReplaceAll(output, "4862", "V")
+    output = strings.ReplaceAll(output, "6842", "n")
+    output = strings.ReplaceAll(output, "4268", "n")
+
+    // half circles
+    output = strings.ReplaceAll(output, "47896", "f")
+    output = strings.ReplaceAll(output, "41236", "g")
+    output = strings.ReplaceAll(output, "63214", "h")
+    output = strings.ReplaceAll(output, "69874", "j")
+
+    // quarter circles
+    output = strings.ReplaceAll(output, "236", "q")
+    output = strings.ReplaceAll(output, "698", "W")
+    output = strings.ReplaceAll(output, "874", "e")
+    output = strings.ReplaceAll(output, "412", "r")
+    output = strings.ReplaceAll(output, "214", "t")
+    output = strings.ReplaceAll(output, "478", "Y")
+    output = strings.ReplaceAll(output, "896", "u")
+    output = strings.ReplaceAll(output, "632", "i")
+
+    // dragon punch / z-motion / shoryu / whatever else these are called
+    output = strings.ReplaceAll(output, "623", "o")
+    output = strings.ReplaceAll(output, "421", "p")
+
+    // double-taps
+    output = strings.ReplaceAll(output, "66", "k")
+    output = strings.ReplaceAll(output, "44", "l")
+
+    return output
+}
+
+func (cv *converter) detokenize(output string) string {
+    // Converts command from a MoveEntry string into movelist.dat glyphs
+    // charge and literal tokens are rendered on their own, so their text can't be mistaken for other tokens
+
+    return map_outside(literal_regex, output, func(tokens string) string {
+        return map_outside_charges(tokens, cv.detokenize_glyphs, cv.detokenize_charge)
+    }, func(literal string) string {
+        return strings.Trim(literal, "`")
+    })
+}
+
+func (cv *converter) detokenize_charge(token string) string {
+    // Converts a charge token into its annotation, following the ChargeFormat option
+    // {dir} is replaced with the held direction's (or button's) glyph, and {time} with how many ticks it has to be held for
+
+    match := charge_regex.FindStringSubmatch(token)
+    var direction string
+
+    for name, digit := range charge_directions {
+        if digit == match[1] {
+            direction = "~" + name
+        }
+    }
+
+    // held buttons are shown with the button's own glyph
+    if direction == "" {
+        direction = cv.detokenize_glyphs(match[1])
+    }
+
+    output := strings.ReplaceAll(cv.opt.ChargeFormat, "{dir}", direction)
+    output = strings.ReplaceAll(output, "{time}", match[2])
+
+    return output
+}
+
+// a token and the movelist.dat glyph it's shown as
+type glyph struct {
+    token string
+    text  string
+}
+
+// held directions
+var held_glyphs = []glyph{
+    {"!", "~DF"}, {"@", "~DB"}, {"#", "~UF"}, {"$", "~UB"},
+    {"%", "~D"}, {"^", "~F"}, {"&", "~U"}, {"*", "~B"},
+}
+
+// held buttons don't have glyphs of their own, so they're turned back into the tokens of the buttons themselves
+var held_button_tokens = []glyph{
+    {"(", "a"}, {")", "b"}, {"<", "c"}, {">", "x"}, {";", "y"}, {"'", "z"}, {"{", "s"}, {"?", "d"}, {"=", "w"},
+}
+
+// motion inputs
+// the order of replacement here matters; longer substrings get matched first to prevent weirdness
+var motion_glyphs = []glyph{
+    // full circles
+    {"v", "_FDF"}, {"n", "_FDB"}, {"V", "_FUF"}, {"N", "_FUB"},
+
+    // half circles
+    {"f", "_HUF"}, {"g", "_HCF"}, {"h", "_HCB"}, {"j", "_HUB"},
+
+    // quarter circles
+    {"q", "_QCF"}, {"W", "_QFU"}, {"e", "_QUB"}, {"r", "_QBD"},
+    {"t", "_QCB"}, {"Y", "_QBU"}, {"u", "_QUF"}, {"i", "_QFD"},
+
+    // dragon punch / z-motion / shoryu / whatever else these are called
+    {"o", "_DSF"}, {"p", "_DSB"},
+
+    // double-taps
+    {"k", "_XFF"}, {"l", "_XBB"},
+}
+
+// regular directions
+var direction_glyphs = []glyph{
+    {"3", "_DF"}, {"1", "_DB"}, {"9", "_UF"}, {"7", "_UB"},
+    {"2", "_D"}, {"6", "_F"}, {"8", "_U"}, {"4", "_B"},
+}
+
+// standard button labels
+var button_glyphs = []glyph{
+    {"a", "^A"}, {"b", "^B"}, {"c", "^C"}, {"x", "^X"}, {"y", "^Y"}, {"z", "^Z"},
+}
+
+// fighting-game-specific button labels, used with UseKP
+var kp_button_glyphs = []glyph{
+    {"a", "^LK"}, {"b", "^MK"}, {"c", "^HK"}, {"x", "^LP"}, {"y", "^MP"}, {"z", "^HP"},
+}
+
+// buttons that lack FG-specific equivalents, and other special glyphs
+var other_glyphs = []glyph{
+    {"s", "^S"}, {"d", "^D"}, {"w", "^W"}, {"+", "_+"},
+}
+
+func replace_glyphs(output string, table []glyph) string {
+    // Replaces every token in the given table with its glyph, in the table's order
+
+    for _, g := range table {
+        output = strings.ReplaceAll(output, g.token, g.text)
+    }
+
+    return output
+}
+
+func glyph_tokens() map[string]string {
+    // Returns the token for every glyph Iguana knows, the reverse of detokenize_glyphs()
+    // both button labels are included, so either can be read back
+
+    tokens := make(map[string]string)
+
+    for _, table := range [][]glyph{held_glyphs, motion_glyphs, direction_glyphs, button_glyphs, kp_button_glyphs, other_glyphs} {
+        for _, g := range table {
+            tokens[g.text] = g.token
+        }
+    }
+
+    return tokens
+}
+
+func (cv *converter) detokenize_glyphs(output string) string {
+    // Converts every non-charge token into movelist.dat glyphs
+
+    output = replace_glyphs(output, held_glyphs)
+    output = replace_glyphs(output, held_button_tokens)
+
+    // convert groups of tokens as motion inputs, then the regular directions and buttons
+    output = replace_glyphs(output, motion_glyphs)
+    output = replace_glyphs(output, direction_glyphs)
+
+    if cv.opt.UseKP {
+        output = replace_glyphs(output, kp_button_glyphs)
+    } else {
+        output = replace_glyphs(output, button_glyphs)
+    }
+
+    output = replace_glyphs(output, other_glyphs)
+
+    // released buttons are done last, so that the text of their annotation doesn't get converted by anything above
+    // {button} is replaced with the glyph of the released button
+    release_glyph_regex, _ := regexp.Compile(`-(\^[A-Z]+)`)
+    output = release_glyph_regex.ReplaceAllStringFunc(output, func(glyph string) string {
+        return strings.ReplaceAll(cv.opt.ReleaseFormat, "{button}", glyph[1:])
+    })
+
+    return output
+}
@@ -0,0 +1,122 @@
+package iguana
+
+import (
+    "strings"
+    "gopkg.in/ini.v1"
+)
+
+// the text Iguana adds to a movelist on its own, keyed by what it's used for
+// these are the English defaults; other languages only need to give the ones they change
+var default_translation = map[string]string{
+    "special":  "Special Moves", // header for regular special moves
+    "air":      "Air Moves",     // header for air-only moves, with StateTypesGroup
+    "hyper":    "Hyper Moves",   // header for moves that use power
+    "in_air":   "Air",           // label for air-only moves, with StateTypesLabel
+    "grounded": "Ground",        // label for ground-only moves, with StateTypesLabel
+    "or":       "or",            // joins alternative inputs for a move
+    "level":    "Lv",            // comes before the level of a super, with PowerStyleLevels
+    "charge":   "charge",        // follows the held direction of a charge input, unless ChargeFormat is set
+    "release":  "release",       // follows a released button, unless ReleaseFormat is set
+}
+
+// the translation keys for each label statetype_label() gives
+var statetype_label_keys = map[string]string{"Air": "in_air", "Ground": "grounded"}
+
+// built-in translations, by language code
+var translations = map[string]map[string]string{
+    "en": {},
+    "ja": {
+        "special":  "必殺技",
+        "air":      "空中技",
+        "hyper":    "超必殺技",
+        "in_air":   "空中",
+        "grounded": "地上",
+        "or":       "または",
+        "charge":   "溜め",
+        "release":  "離す",
+    },
+    "es": {
+        "special":  "Movimientos Especiales",
+        "air":      "Movimientos Aéreos",
+        "hyper":    "Súper Movimientos",
+        "in_air":   "Aire",
+        "grounded": "Suelo",
+        "or":       "o",
+        "level":    "Nv",
+        "charge":   "carga",
+        "release":  "soltar",
+    },
+    "pt": {
+        "special":  "Golpes Especiais",
+        "air":      "Golpes Aéreos",
+        "hyper":    "Super Golpes",
+        "in_air":   "Ar",
+        "grounded": "Chão",
+        "or":       "ou",
+        "level":    "Nv",
+        "charge":   "carga",
+        "release":  "soltar",
+    },
+}
+
+// Languages returns the codes of every built-in translation
+func Languages() []string {
+    return []string{"en", "ja", "es", "pt"}
+}
+
+// LoadTranslation reads a translation file for use as Options.Translation
+// the file is made of "name = text" lines, using the same names as the built-in translations (special, air, hyper, in_air, grounded, or, level, charge, release)
+func LoadTranslation(path string) (map[string]string, error) {
+    file_data, err := read_file(path)
+    if err != nil {
+        return nil, err
+    }
+
+    parsed_ini, err := load_ini(path, file_data, ini.LoadOptions{AllowNonUniqueSections: true, SkipUnrecognizableLines: true})
+    if err != nil {
+        return nil, err
+    }
+
+    translation := make(map[string]string)
+
+    for _, section := range parsed_ini.Sections() {
+        for _, key_name := range section.KeyStrings() {
+            translation[strings.ToLower(key_name)] = strings.Trim(section.Key(key_name).String(), `"`)
+        }
+    }
+
+    return translation, nil
+}
+
+func (cv *converter) load_translation() {
+    // Works out the text to use for this conversion, from the English defaults, the chosen language, then the user's own translation
+
+    cv.text = make(map[string]string)
+
+    for key, value := range default_translation {
+        cv.text[key] = value
+    }
+
+    if cv.opt.Language != "" {
+        table, ok := translations[strings.ToLower(cv.opt.Language)]
+        if !ok {
+            cv.debug("No built-in translation for", cv.opt.Language + ", using English instead")
+        }
+
+        for key, value := range table {
+            cv.text[key] = value
+        }
+    }
+
+    for key, value := range cv.opt.Translation {
+        if _, ok := default_translation[key]; !ok {
+            cv.debug("Unknown translation key:", key)
+            continue
+        }
+        cv.text[key] = value
+    }
+}
+
+func (cv *converter) translate(key string) string {
+    return cv.text[key]
+}
@@ -0,0 +1,38 @@
+package iguana
+
+import (
+    "path/filepath"
+    "strings"
+    "testing"
+)
+
+func TestTranslatedAnnotations(t *testing.T) {
+    tests := []struct {
+        name    string
+        options func(opt *Options)
+        want    []string
+    }{
+        {"english", func(opt *Options) {}, []string{"Charge Move\t\t\t[~B] charge, _F^X", "Release Move\t\t\t_QCF^X(release)"}},
+        {"japanese", func(opt *Options) {opt.Language = "ja"}, []string{"Charge Move\t\t\t[~B] 溜め, _F^X", "Release Move\t\t\t_QCF^X(離す)"}},
+        {"own translation", func(opt *Options) {opt.Translation = map[string]string{"charge": "hold", "release": "let go"}}, []string{"[~B] hold, _F^X", "_QCF^X(let go)"}},
+        {"own formats", func(opt *Options) {opt.Language = "ja"; opt.ChargeFormat = "{dir}{time} "; opt.ReleaseFormat = "~{button}"}, []string{"~B30 _F^X", "_QCF~^X"}},
+    }
+
+    for _, test := range tests {
+        t.Run(test.name, func(t *testing.T) {
+            opt := DefaultOptions()
+            test.options(&opt)
+
+            movelist, err := Convert(filepath.Join("res", "test.cmd"), opt)
+            if err != nil {
+                t.Fatal(err)
+            }
+
+            for _, want := range test.want {
+                if !strings.Contains(movelist, want) {
+                    t.Errorf("got movelist:\n%s\nwant it to contain %q", movelist, want)
+                }
+            }
+        })
+    }
+}
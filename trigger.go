@@ -0,0 +1,528 @@
+package iguana
+
+import (
+    "errors"
+    "math"
+    "strconv"
+    "strings"
+)
+
+// the kinds of nodes a trigger expression can be made of
+const (
+    expr_number   = iota // a numeric literal, e.g. 1000
+    expr_string          // a quoted string, e.g. "QCF_x"
+    expr_ident           // a bare trigger name, e.g. statetype or A
+    expr_call            // a trigger with arguments, e.g. var(1) or ifelse(a, b, c)
+    expr_unary           // !, - or ~ applied to one argument
+    expr_binary          // any operator with two arguments, e.g. && or >=
+    expr_range           // an interval after = or !=, e.g. [1000, 2999]; value holds the brackets used
+    expr_redirect        // a redirected trigger, e.g. root, stateno
+)
+
+// a single node of a parsed MUGEN trigger expression
+type expr struct {
+    kind  int
+    value string
+    args  []*expr
+}
+
+// keywords that can redirect a trigger to another player with a comma, e.g. "root, var(1)"
+var redirect_keywords = []string{"parent", "root", "helper", "target", "partner", "enemy", "enemynear", "playerid", "rootparent"}
+
+// binary operators by precedence, loosest first (see MUGEN's trigger documentation)
+var binary_precedence = [][]string{
+    {":="},
+    {"||"},
+    {"^^"},
+    {"&&"},
+    {"|"},
+    {"^"},
+    {"&"},
+    {"=", "!="},
+    {"<", "<=", ">", ">="},
+    {"+", "-"},
+    {"*", "/", "%"},
+    {"**"},
+}
+
+// every operator the lexer understands, longer ones first so that e.g. "!=" isn't read as "!" and "="
+var operators = []string{":=", "||", "^^", "&&", "!=", "<=", ">=", "**", "=", "<", ">", "+", "-", "*", "/", "%", "!", "~", "&", "|", "^", "(", ")", "[", "]", ","}
+
+// a single token of a trigger expression
+type trigger_token struct {
+    kind  int // expr_number, expr_string or expr_ident, or -1 for operators
+    value string
+}
+
+func lex_trigger(input string) ([]trigger_token, error) {
+    // Splits a trigger expression into tokens
+
+    var tokens []trigger_token
+    i := 0
+
+    for i < len(input) {
+        char := input[i]
+
+        switch {
+        case char == ' ' || char == '\t' || char == '\r' || char == '\n':
+            i++
+
+        case char == '"':
+            end := strings.Index(input[i+1:], `"`)
+            if end == -1 {
+                return nil, errors.New("unterminated string in trigger: " + input)
+            }
+            tokens = append(tokens, trigger_token{expr_string, input[i+1 : i+1+end]})
+            i += end + 2
+
+        case (char >= '0' && char <= '9') || char == '.':
+            start := i
+            for i < len(input) && ((input[i] >= '0' && input[i] <= '9') || input[i] == '.') {
+                i++
+            }
+            tokens = append(tokens, trigger_token{expr_number, input[start:i]})
+
+        case char == '_' || (char >= 'a' && char <= 'z') || (char >= 'A' && char <= 'Z'):
+            // identifiers can contain dots, as in const(data.power) or GetHitVar(fall.yvel)
+            start := i
+            for i < len(input) && (input[i] == '_' || input[i] == '.' || (input[i] >= 'a' && input[i] <= 'z') || (input[i] >= 'A' && input[i] <= 'Z') || (input[i] >= '0' && input[i] <= '9')) {
+                i++
+            }
+            tokens = append(tokens, trigger_token{expr_ident, input[start:i]})
+
+        default:
+            found := false
+
+            for _, op := range operators {
+                if strings.HasPrefix(input[i:], op) {
+                    tokens = append(tokens, trigger_token{-1, op})
+                    i += len(op)
+                    found = true
+                    break
+                }
+            }
+
+            if !found {
+                return nil, errors.New("unexpected character '" + string(char) + "' in trigger: " + input)
+            }
+        }
+    }
+
+    return tokens, nil
+}
+
+// walks over a list of tokens, building an expression tree out of them
+type trigger_parser struct {
+    tokens []trigger_token
+    pos    int
+}
+
+func (p *trigger_parser) peek() string {
+    // Returns the next operator without consuming it, or an empty string if the next token isn't an operator
+    if p.pos < len(p.tokens) && p.tokens[p.pos].kind == -1 {
+        return p.tokens[p.pos].value
+    }
+    return ""
+}
+
+func (p *trigger_parser) expect(op string) error {
+    if p.peek() != op {
+        return errors.New("expected '" + op + "' in trigger")
+    }
+    p.pos++
+    return nil
+}
+
+func parse_trigger(input string) (*expr, error) {
+    // Parses a trigger expression (the value of a triggerall or triggerN key) into a tree
+    // anything left over after a complete expression (like the ", >= 0" of "AnimElem = 2, >= 0") is ignored
+
+    tokens, err := lex_trigger(input)
+    if err != nil {
+        return nil, err
+    }
+
+    if len(tokens) == 0 {
+        return nil, errors.New("empty trigger")
+    }
+
+    p := &trigger_parser{tokens: tokens}
+    return p.parse_binary(0)
+}
+
+func (p *trigger_parser) parse_binary(level int) (*expr, error) {
+    // Parses a chain of binary operators from the given precedence level and tighter
+
+    if level >= len(binary_precedence) {
+        return p.parse_unary()
+    }
+
+    left, err := p.parse_binary(level + 1)
+    if err != nil {
+        return nil, err
+    }
+
+    for {
+        op := p.peek()
+        matched := false
+
+        for _, candidate := range binary_precedence[level] {
+            if op == candidate {
+                matched = true
+            }
+        }
+
+        if !matched {
+            return left, nil
+        }
+        p.pos++
+
+        var right *expr
+
+        // = and != can also compare against an interval, e.g. "power = [1000, 2999]"
+        if (op == "=" || op == "!=") && (p.peek() == "[" || p.peek() == "(") {
+            right, err = p.parse_range()
+        } else {
+            right, err = p.parse_binary(level + 1)
+        }
+
+        if err != nil {
+            return nil, err
+        }
+
+        left = &expr{kind: expr_binary, value: op, args: []*expr{left, right}}
+    }
+}
+
+func (p *trigger_parser) parse_range() (*expr, error) {
+    // Parses an interval like [1000, 2999] or (0, 10]
+    // a parenthesis without a comma inside is just a regular parenthesized expression, so that's handled here too
+
+    open := p.peek()
+    p.pos++
+
+    low, err := p.parse_binary(0)
+    if err != nil {
+        return nil, err
+    }
+
+    if open == "(" && p.peek() == ")" {
+        p.pos++
+        return low, nil
+    }
+
+    if err := p.expect(","); err != nil {
+        return nil, err
+    }
+
+    high, err := p.parse_binary(0)
+    if err != nil {
+        return nil, err
+    }
+
+    close := p.peek()
+    if close != "]" && close != ")" {
+        return nil, errors.New("unterminated range in trigger")
+    }
+    p.pos++
+
+    return &expr{kind: expr_range, value: open + close, args: []*expr{low, high}}, nil
+}
+
+func (p *trigger_parser) parse_unary() (*expr, error) {
+    op := p.peek()
+
+    if op == "!" || op == "-" || op == "~" {
+        p.pos++
+
+        arg, err := p.parse_unary()
+        if err != nil {
+            return nil, err
+        }
+
+        return &expr{kind: expr_unary, value: op, args: []*expr{arg}}, nil
+    }
+
+    return p.parse_primary()
+}
+
+func (p *trigger_parser) parse_primary() (*expr, error) {
+    if p.pos >= len(p.tokens) {
+        return nil, errors.New("unexpected end of trigger")
+    }
+
+    token := p.tokens[p.pos]
+    p.pos++
+
+    switch token.kind {
+    case expr_number, expr_string:
+        return &expr{kind: token.kind, value: token.value}, nil
+
+    case expr_ident:
+        node := &expr{kind: expr_ident, value: token.value}
+
+        // some triggers take a component after a space, e.g. "pos y" or "p2bodydist x"
+        if p.pos < len(p.tokens) && p.tokens[p.pos].kind == expr_ident {
+            component := strings.ToLower(p.tokens[p.pos].value)
+            if component == "x" || component == "y" || component == "z" {
+                node.value += " " + p.tokens[p.pos].value
+                p.pos++
+            }
+        }
+
+        // function-style triggers, e.g. var(1)
+        if p.peek() == "(" {
+            p.pos++
+            node.kind = expr_call
+
+            for p.peek() != ")" {
+                arg, err := p.parse_binary(0)
+                if err != nil {
+                    return nil, err
+                }
+                node.args = append(node.args, arg)
+
+                if p.peek() == "," {
+                    p.pos++
+                } else if p.peek() != ")" {
+                    return nil, errors.New("expected ')' after arguments of " + token.value)
+                }
+            }
+            p.pos++
+        }
+
+        // redirections, e.g. "root, var(1)" or "helper(1000), stateno"
+        if p.peek() == "," && is_redirect_keyword(token.value) {
+            p.pos++
+
+            target, err := p.parse_unary()
+            if err != nil {
+                return nil, err
+            }
+
+            return &expr{kind: expr_redirect, value: token.value, args: []*expr{node, target}}, nil
+        }
+
+        return node, nil
+    }
+
+    if token.value == "(" {
+        inner, err := p.parse_binary(0)
+        if err != nil {
+            return nil, err
+        }
+
+        if err := p.expect(")"); err != nil {
+            return nil, err
+        }
+
+        return inner, nil
+    }
+
+    return nil, errors.New("unexpected '" + token.value + "' in trigger")
+}
+
+func is_redirect_keyword(name string) bool {
+    for _, keyword := range redirect_keywords {
+        if strings.EqualFold(name, keyword) {
+            return true
+        }
+    }
+    return false
+}
+
+func (e *expr) constant() (float64, bool) {
+    // Works out the value of this node if it's made only of numbers, e.g. 3*1000
+    // returns false if it depends on anything that's only known in-game
+
+    return e.constant_with(nil)
+}
+
+func (e *expr) constant_with(known map[string]float64) (float64, bool) {
+    // Same as constant(), but triggers in the given map (e.g. "powermax" or "const(data.power)") count as numbers too
+
+    switch e.kind {
+    case expr_number:
+        value, err := strconv.ParseFloat(e.value, 64)
+        return value, err == nil
+
+    case expr_ident:
+        value, ok := known[strings.ToLower(e.value)]
+        return value, ok
+
+    case expr_call:
+        if len(e.args) == 1 && e.args[0].kind == expr_ident {
+            value, ok := known[strings.ToLower(e.value + "(" + e.args[0].value + ")")]
+            return value, ok
+        }
+
+    case expr_unary:
+        value, ok := e.args[0].constant_with(known)
+        if !ok || e.value != "-" {
+            return 0, false
+        }
+        return -value, true
+
+    case expr_binary:
+        left, left_ok := e.args[0].constant_with(known)
+        right, right_ok := e.args[1].constant_with(known)
+        if !left_ok || !right_ok {
+            return 0, false
+        }
+
+        switch e.value {
+        case "+":
+            return left + right, true
+        case "-":
+            return left - right, true
+        case "*":
+            return left * right, true
+        case "/":
+            if right == 0 {return 0, false}
+            return left / right, true
+        case "%":
+            if right == 0 {return 0, false}
+            return math.Mod(left, right), true
+        case "**":
+            return math.Pow(left, right), true
+        }
+    }
+
+    return 0, false
+}
+
+func parse_constant(input string) (float64, bool) {
+    // Parses a parameter (like the value of a PowerAdd) and works out its value, if it's made only of numbers
+
+    tree, err := parse_trigger(input)
+    if err != nil {
+        return 0, false
+    }
+
+    return tree.constant()
+}
+
+func (e *expr) command_name() (string, bool) {
+    // Checks if this node is a command comparison (command = "name"), returning the name of the command if so
+
+    op, right, ok := e.normalize_comparison("command")
+
+    if ok && op == "=" && right.kind == expr_string {
+        return right.value, true
+    }
+
+    return "", false
+}
+
+// comparison operators with their sides swapped, for comparisons written like "1000 <= power"
+var swapped_comparisons = map[string]string{">=": "<=", ">": "<", "<=": ">=", "<": ">", "=": "=", "!=": "!="}
+
+func (e *expr) normalize_comparison(name string) (string, *expr, bool) {
+    // Checks if this node compares the given trigger against something, returning the operator and what it's compared with
+    // MUGEN allows the comparison to be written either way around, so "1000 <= power" comes back the same as "power >= 1000"
+
+    if e.kind != expr_binary {
+        return "", nil, false
+    }
+
+    op, ok := swapped_comparisons[e.value]
+    if !ok {
+        return "", nil, false
+    }
+
+    left, right := e.args[0], e.args[1]
+
+    if left.kind == expr_ident && strings.EqualFold(left.value, name) {
+        return e.value, right, true
+    }
+
+    if right.kind == expr_ident && strings.EqualFold(right.value, name) {
+        return op, left, true
+    }
+
+    return "", nil, false
+}
+
+// past this many alternatives, a trigger is too convoluted to be worth following any further
+const max_alternatives = 64
+
+func (e *expr) command_alternatives() [][]string {
+    // Returns the sets of commands that can satisfy this expression, e.g. (a || b) && c becomes [[a c] [b c]]
+    // each set is one way of activating the move, and every command in a set has to be input
+    // anything that isn't a command comparison (including negated commands) doesn't add a requirement, so it's an empty set
+
+    if name, ok := e.command_name(); ok {
+        return [][]string{{name}}
+    }
+
+    if e.kind == expr_binary {
+        switch e.value {
+        case "||", "^^":
+            return append(e.args[0].command_alternatives(), e.args[1].command_alternatives()...)
+
+        case "&&":
+            return and_alternatives(e.args[0].command_alternatives(), e.args[1].command_alternatives())
+        }
+    }
+
+    return [][]string{{}}
+}
+
+func and_alternatives(left [][]string, right [][]string) [][]string {
+    // Combines two sets of alternatives that both have to be satisfied
+
+    var output [][]string
+
+    for _, l := range left {
+        for _, r := range right {
+            combined := append([]string{}, l...)
+
+            for _, name := range r {
+                if !contains_string(combined, name) {
+                    combined = append(combined, name)
+                }
+            }
+
+            output = append(output, combined)
+
+            if len(output) >= max_alternatives {
+                return output
+            }
+        }
+    }
+
+    return output
+}
+
+func clean_alternatives(input [][]string) [][]string {
+    // Removes duplicate alternatives, as well as alternatives without any commands at all
+    // a move that can be activated without a command (e.g. command = "x" || var(59) = 1) is usually just an AI shortcut
+
+    var output [][]string
+
+    for _, alt := range input {
+        if len(alt) == 0 {continue}
+
+        duplicate := false
+        for _, existing := range output {
+            if strings.Join(existing, "\n") == strings.Join(alt, "\n") {
+                duplicate = true
+            }
+        }
+
+        if !duplicate {
+            output = append(output, alt)
+        }
+    }
+
+    return output
+}
+
+func contains_string(input []string, value string) bool {
+    for _, i := range input {
+        if i == value {
+            return true
+        }
+    }
+    return false
+}
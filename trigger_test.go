@@ -0,0 +1,174 @@
+package iguana
+
+import (
+    "fmt"
+    "strings"
+    "testing"
+)
+
+func format_expr(e *expr) string {
+    // Writes a tree out with every node in parentheses, so that tests can see how an expression was grouped
+
+    switch e.kind {
+    case expr_string:
+        return `"` + e.value + `"`
+    case expr_call:
+        var args []string
+        for _, arg := range e.args {
+            args = append(args, format_expr(arg))
+        }
+        return e.value + "(" + strings.Join(args, " ") + ")"
+    case expr_unary:
+        return "(" + e.value + " " + format_expr(e.args[0]) + ")"
+    case expr_binary:
+        return "(" + e.value + " " + format_expr(e.args[0]) + " " + format_expr(e.args[1]) + ")"
+    case expr_range:
+        return e.value[0:1] + format_expr(e.args[0]) + " " + format_expr(e.args[1]) + e.value[1:2]
+    case expr_redirect:
+        return "(" + format_expr(e.args[0]) + ", " + format_expr(e.args[1]) + ")"
+    }
+
+    return e.value
+}
+
+func TestParseTrigger(t *testing.T) {
+    tests := []struct {
+        name  string
+        input string
+        want  string
+    }{
+        // precedence
+        {"and before or", `command = "x" && power >= 1000 || ctrl`, `(|| (&& (= command "x") (>= power 1000)) ctrl)`},
+        {"parentheses", `command = "x" && (power >= 1000 || ctrl)`, `(&& (= command "x") (|| (>= power 1000) ctrl))`},
+        {"xor between or and and", `a || b ^^ c && d`, `(|| a (^^ b (&& c d)))`},
+        {"arithmetic", `1 + 2 * 3 ** 2`, `(+ 1 (* 2 (** 3 2)))`},
+        {"left to right", `10 - 2 - 3`, `(- (- 10 2) 3)`},
+        {"comparison before equality", `a < b = c`, `(= (< a b) c)`},
+        {"bitwise before equality", `a = 1 | 2`, `(| (= a 1) 2)`},
+        {"unary", `!ctrl && -power < -1000`, `(&& (! ctrl) (< (- power) (- 1000)))`},
+        {"reversed comparison", `3*1000 <= Power && statetype != A`, `(&& (<= (* 3 1000) Power) (!= statetype A))`},
+
+        // ranges
+        {"closed range", `stateno = [1000, 1010]`, `(= stateno [1000 1010])`},
+        {"half open range", `power != (0, 1000]`, `(!= power (0 1000])`},
+        {"range with expressions", `stateno = [1000 + 10, 2000 - 1)`, `(= stateno [(+ 1000 10) (- 2000 1)))`},
+        {"parenthesized value", `stateno = (1000)`, `(= stateno 1000)`},
+
+        // redirects
+        {"redirect", `root, var(1) = 2`, `(= (root, var(1)) 2)`},
+        {"redirect with id", `helper(1000), stateno = 200`, `(= (helper(1000), stateno) 200)`},
+        {"redirect in a chain", `ctrl && parent, power >= 1000`, `(&& ctrl (>= (parent, power) 1000))`},
+
+        // the rest
+        {"trigger with a component", `pos y >= 0`, `(>= pos y 0)`},
+        {"function arguments", `ifelse(var(1), 1, 0)`, `ifelse(var(1) 1 0)`},
+        {"leftovers ignored", `AnimElem = 2, >= 0`, `(= AnimElem 2)`},
+    }
+
+    for _, test := range tests {
+        t.Run(test.name, func(t *testing.T) {
+            tree, err := parse_trigger(test.input)
+            if err != nil {
+                t.Fatalf("parse_trigger(%q) failed: %v", test.input, err)
+            }
+
+            if got := format_expr(tree); got != test.want {
+                t.Errorf("parse_trigger(%q) = %s, want %s", test.input, got, test.want)
+            }
+        })
+    }
+}
+
+func TestParseTriggerErrors(t *testing.T) {
+    tests := []string{
+        ``,
+        `(1 + 2`,
+        `stateno = [1000, 1010`,
+        `var(1`,
+        `command = `,
+    }
+
+    for _, input := range tests {
+        if tree, err := parse_trigger(input); err == nil {
+            t.Errorf("parse_trigger(%q) = %s, want an error", input, format_expr(tree))
+        }
+    }
+}
+
+func TestTriggerConstant(t *testing.T) {
+    tests := []struct {
+        input string
+        want  float64
+        ok    bool
+    }{
+        {`3*1000`, 3000, true},
+        {`-(500 + 500)`, -1000, true},
+        {`2 ** 3 % 5`, 3, true},
+        {`1000 / 0`, 0, false},
+        {`powermax / 3`, 0, false},
+    }
+
+    for _, test := range tests {
+        got, ok := parse_constant(test.input)
+        if got != test.want || ok != test.ok {
+            t.Errorf("parse_constant(%q) = %v, %v, want %v, %v", test.input, got, ok, test.want, test.ok)
+        }
+    }
+}
+
+func TestCommandAlternatives(t *testing.T) {
+    tests := []struct {
+        input string
+        want  string
+    }{
+        {`command = "a"`, `[[a]]`},
+        {`"a" = command`, `[[a]]`},
+        {`command = "a" && command = "b"`, `[[a b]]`},
+        {`(command = "a" || command = "b") && command = "c"`, `[[a c] [b c]]`},
+        {`command = "a" || var(59) = 1`, `[[a] []]`},
+        {`command != "a"`, `[[]]`},
+    }
+
+    for _, test := range tests {
+        tree, err := parse_trigger(test.input)
+        if err != nil {
+            t.Fatalf("parse_trigger(%q) failed: %v", test.input, err)
+        }
+
+        if got := fmt.Sprint(tree.command_alternatives()); got != test.want {
+            t.Errorf("command_alternatives(%q) = %s, want %s", test.input, got, test.want)
+        }
+    }
+}
+
+func TestNormalizeComparison(t *testing.T) {
+    tests := []struct {
+        input string
+        name  string
+        want  string
+    }{
+        {`power >= 1000`, "power", `>= 1000`},
+        {`1000 <= Power`, "power", `>= 1000`},
+        {`3*1000 > power`, "power", `< (* 3 1000)`},
+        {`"QCF_x" = command`, "command", `= "QCF_x"`},
+        {`stateno != [200, 210]`, "stateno", `!= [200 210]`},
+        {`power + 1 >= 1000`, "power", ``},
+        {`power && ctrl`, "power", ``},
+    }
+
+    for _, test := range tests {
+        tree, err := parse_trigger(test.input)
+        if err != nil {
+            t.Fatalf("parse_trigger(%q) failed: %v", test.input, err)
+        }
+
+        var got string
+        if op, other, ok := tree.normalize_comparison(test.name); ok {
+            got = op + " " + format_expr(other)
+        }
+
+        if got != test.want {
+            t.Errorf("normalize_comparison(%q, %q) = %q, want %q", test.input, test.name, got, test.want)
+        }
+    }
+}
@@ -0,0 +1,416 @@
+package iguana
+
+import (
+    "path/filepath"
+    "strconv"
+    "strings"
+)
+
+// ZSS (Zantei State Script) is Ikemen GO's alternative to writing states as INI sections
+// instead of state controllers with trigger keys, the controllers are written inside of if blocks, e.g.
+//
+//     [StateDef -1]
+//     # Fireball
+//     if command = "QCF_x" && statetype != A && ctrl {
+//         changeState{value: 1000}
+//     }
+//
+// so rather than going through the INI library, these files get a small parser of their own
+
+// a single state controller from a ZSS file, along with every condition it's nested under
+type zss_controller struct {
+    name       string
+    params     map[string]string // parameters by lowercase name, e.g. "value"
+    conditions []*expr           // every one of these has to be true for the controller to run
+    label      string            // the comment line above the controller (or the block it's in), if there is one
+    line       int               // 1-based line number the controller starts on
+}
+
+// a single [StateDef] (or [Function]) of a ZSS file
+type zss_state struct {
+    header string // what's between the brackets, e.g. "StateDef 200; type: S"
+    body   string
+    line   int    // 1-based line number of the header
+}
+
+// walks over the code of a single ZSS state
+type zss_parser struct {
+    cv      *converter
+    input   string
+    pos     int
+    comment string // the last comment that had a line to itself, which becomes the label of the next statement
+    line    int    // the line number the input starts on, so that controllers can say where they are in the file
+}
+
+func is_zss(path string) bool {
+    // Checks if the given state file is written in ZSS rather than as INI sections
+
+    return strings.EqualFold(filepath.Ext(path), ".zss")
+}
+
+func (cv *converter) scrape_zss_files(files []string, cmd string, costs map[int]int) []Move {
+    // Returns the moves from the [StateDef -1] of any ZSS files in the given state files
+    // the power costs of the states they define are added to the given map along the way
+
+    var moves []Move
+
+    for _, path := range files {
+        if !is_zss(path) || filepath.Clean(path) == filepath.Clean(cmd) {continue}
+
+        // like with INI state files, a missing ZSS file only means the movelist will be missing whatever was in it
+        file_data, err := read_file(path)
+        if err != nil {
+            cv.debug("Couldn't read state file:", err)
+            continue
+        }
+
+        cv.debug("Scraping ZSS state file", path, "\n"+hr)
+        moves = append(moves, cv.scrape_zss(path, string(file_data), costs)...)
+    }
+
+    return moves
+}
+
+func (cv *converter) scrape_zss(path string, input string, costs map[int]int) []Move {
+    // Returns the moves defined in the [StateDef -1] of a single ZSS file, and records the power costs of its other states
+
+    var moves []Move
+
+    for _, state := range split_zss_states(input) {
+        params := strings.Split(state.header, ";")
+        name := strings.ToLower(strings.Join(strings.Fields(params[0]), " "))
+
+        // functions and anything else that isn't a state can't have moves or costs
+        if !strings.HasPrefix(name, "statedef ") {continue}
+
+        statedef, err := strconv.Atoi(strings.TrimSpace(name[len("statedef "):]))
+        if err != nil {continue}
+
+        parser := zss_parser{cv: cv, input: state.body, line: state.line + 1}
+        controllers := parser.parse_statements(false, nil, "")
+
+        if statedef != -1 {
+            for _, param := range params[1:] {
+                if key, value, ok := split_zss_param(param); ok && key == "poweradd" {
+                    record_cost(costs, statedef, value)
+                }
+            }
+
+            for _, controller := range controllers {
+                if strings.EqualFold(controller.name, "PowerAdd") {
+                    record_cost(costs, statedef, controller.params["value"])
+                }
+            }
+            continue
+        }
+
+        for _, controller := range controllers {
+            if !strings.EqualFold(controller.name, "ChangeState") {continue}
+
+            move := Move{name: controller.label, value: strings.TrimSpace(controller.params["value"]), triggerall: controller.conditions}
+            move.source = Source{File: path, Line: controller.line, Section: strings.TrimSpace(params[0])}
+
+            // ZSS controllers don't have names of their own, so unlabelled ones are named after where they go
+            if move.name == "" {
+                move.name = "State " + move.value
+            }
+
+            cv.analyze_move(&move)
+
+            if len(move.commands) > 0 {
+                cv.debug("Found move:", move.name, move.commands, "statetype mask:", move.statetypes, "ctrl mask:", move.ctrl, "power:", move.power)
+                moves = append(moves, move)
+            }
+        }
+    }
+
+    return moves
+}
+
+func split_zss_states(input string) []zss_state {
+    // Splits a ZSS file into its states
+
+    var states []zss_state
+    var state *zss_state
+    var body []string
+
+    for i, line := range strings.Split(input, "\n") {
+        trimmed := strings.TrimSpace(line)
+
+        if strings.HasPrefix(trimmed, "[") && strings.Contains(trimmed, "]") {
+            if state != nil {
+                state.body = strings.Join(body, "\n")
+                states = append(states, *state)
+            }
+
+            state = &zss_state{header: trimmed[1:strings.LastIndex(trimmed, "]")], line: i + 1}
+            body = nil
+            continue
+        }
+
+        body = append(body, line)
+    }
+
+    if state != nil {
+        state.body = strings.Join(body, "\n")
+        states = append(states, *state)
+    }
+
+    return states
+}
+
+func split_zss_param(input string) (string, string, bool) {
+    // Splits a "name: value" parameter, returning the name in lowercase
+
+    i := strings.Index(input, ":")
+    if i == -1 {
+        return "", "", false
+    }
+
+    return strings.ToLower(strings.TrimSpace(input[:i])), strings.TrimSpace(input[i+1:]), true
+}
+
+func (p *zss_parser) parse_statements(in_block bool, conditions []*expr, label string) []zss_controller {
+    // Parses statements until the end of the current block (or the end of the state), returning every controller found
+    // statements that can't hold controllers, like let and call, are skipped over
+
+    var controllers []zss_controller
+
+    for {
+        p.skip_space()
+
+        if p.pos >= len(p.input) {
+            return controllers
+        }
+
+        if p.input[p.pos] == '}' {
+            p.pos++
+            if in_block {
+                return controllers
+            }
+            continue
+        }
+
+        if p.input[p.pos] == ';' {
+            p.pos++
+            continue
+        }
+
+        // a comment right above a statement labels it, and everything inside of it that doesn't have a label of its own
+        statement_label := label
+        if p.comment != "" {
+            statement_label = p.comment
+            p.comment = ""
+        }
+
+        word := p.read_word()
+
+        switch strings.ToLower(word) {
+        case "":
+            // not something we understand, so move past it
+            p.pos++
+
+        case "if":
+            controllers = append(controllers, p.parse_if(conditions, statement_label)...)
+
+        case "ignorehitpause":
+            // only changes how the statement after it runs, which doesn't matter here
+            p.comment = statement_label
+
+        case "persistent":
+            p.skip_space()
+            if p.pos < len(p.input) && p.input[p.pos] == '(' {
+                p.pos++
+                p.read_until(")")
+                p.skip_char()
+            }
+            p.comment = statement_label
+
+        default:
+            p.skip_space()
+
+            if p.pos < len(p.input) && p.input[p.pos] == '{' {
+                p.pos++
+                line := p.line + strings.Count(p.input[:p.pos], "\n")
+                controllers = append(controllers, zss_controller{name: word, params: p.parse_params(), conditions: conditions, label: statement_label, line: line})
+                continue
+            }
+
+            // let, call and the like; if one turns out to have a block (e.g. switch), the whole block is skipped
+            p.read_until(";\n{")
+            if p.pos < len(p.input) && p.input[p.pos] == '{' {
+                p.pos++
+                p.parse_statements(true, conditions, statement_label)
+            }
+        }
+    }
+}
+
+func (p *zss_parser) parse_if(conditions []*expr, label string) []zss_controller {
+    // Parses an if statement, along with any else if and else branches after it
+    // each branch only runs if every branch before it was false, so their conditions are carried over negated
+
+    var controllers []zss_controller
+    var previous []*expr
+
+    for {
+        text := p.read_until("{")
+        p.skip_char()
+
+        branch := append(append([]*expr{}, conditions...), previous...)
+
+        tree, err := parse_trigger(text)
+        if err != nil {
+            p.cv.debug("ZSS condition", strings.TrimSpace(text), "couldn't be parsed:", err)
+        } else {
+            branch = append(branch, tree)
+            previous = append(previous, &expr{kind: expr_unary, value: "!", args: []*expr{tree}})
+        }
+
+        controllers = append(controllers, p.parse_statements(true, branch, label)...)
+
+        // look ahead for an else, and put everything back if there isn't one
+        start, comment := p.pos, p.comment
+        p.skip_space()
+
+        if !strings.EqualFold(p.read_word(), "else") {
+            p.pos, p.comment = start, comment
+            return controllers
+        }
+
+        p.skip_space()
+        else_start := p.pos
+
+        if strings.EqualFold(p.read_word(), "if") {
+            continue
+        }
+
+        p.pos = else_start
+        p.read_until("{")
+        p.skip_char()
+
+        otherwise := append(append([]*expr{}, conditions...), previous...)
+        return append(controllers, p.parse_statements(true, otherwise, label)...)
+    }
+}
+
+func (p *zss_parser) parse_params() map[string]string {
+    // Parses the parameters of a controller up to its closing brace, e.g. "value: 1000; ctrl: 0"
+
+    params := make(map[string]string)
+
+    for {
+        text := p.read_until(";}")
+
+        if key, value, ok := split_zss_param(text); ok {
+            params[key] = value
+        }
+
+        if p.pos >= len(p.input) {
+            return params
+        }
+
+        p.pos++
+        if p.input[p.pos-1] == '}' {
+            return params
+        }
+    }
+}
+
+func (p *zss_parser) skip_space() {
+    // Moves past any whitespace and comments, remembering the last comment that had a line to itself
+
+    for p.pos < len(p.input) {
+        char := p.input[p.pos]
+
+        if char == ' ' || char == '\t' || char == '\r' || char == '\n' {
+            p.pos++
+            continue
+        }
+
+        if char != '#' {
+            return
+        }
+
+        line_start := strings.LastIndex(p.input[:p.pos], "\n") + 1
+        own_line := strings.TrimSpace(p.input[line_start:p.pos]) == ""
+
+        end := strings.Index(p.input[p.pos:], "\n")
+        if end == -1 {
+            end = len(p.input) - p.pos
+        }
+
+        if own_line {
+            p.comment = strings.TrimSpace(strings.TrimLeft(p.input[p.pos:p.pos+end], "#"))
+        }
+
+        p.pos += end
+    }
+}
+
+func (p *zss_parser) skip_char() {
+    // Moves past the character read_until stopped at, unless it stopped at the end of the input
+
+    if p.pos < len(p.input) {
+        p.pos++
+    }
+}
+
+func (p *zss_parser) read_word() string {
+    // Reads a keyword or controller name
+
+    start := p.pos
+
+    for p.pos < len(p.input) {
+        char := p.input[p.pos]
+        if !((char >= 'a' && char <= 'z') || (char >= 'A' && char <= 'Z') || (char >= '0' && char <= '9') || char == '_' || char == '.') {
+            break
+        }
+        p.pos++
+    }
+
+    return p.input[start:p.pos]
+}
+
+func (p *zss_parser) read_until(stops string) string {
+    // Reads up to (but not including) the first of the given characters that isn't inside of parentheses, brackets or a string
+    // comments are left out of what's returned, so that conditions spanning several lines still parse
+
+    var output strings.Builder
+    depth := 0
+
+    for p.pos < len(p.input) {
+        char := p.input[p.pos]
+
+        if depth == 0 && strings.IndexByte(stops, char) != -1 {
+            break
+        }
+
+        switch char {
+        case '(', '[':
+            depth++
+        case ')', ']':
+            if depth > 0 {depth--}
+        case '"':
+            end := strings.IndexByte(p.input[p.pos+1:], '"')
+            if end != -1 {
+                output.WriteString(p.input[p.pos : p.pos+end+2])
+                p.pos += end + 2
+                continue
+            }
+        case '#':
+            end := strings.IndexByte(p.input[p.pos:], '\n')
+            if end == -1 {
+                p.pos = len(p.input)
+                continue
+            }
+            p.pos += end
+            continue
+        }
+
+        output.WriteByte(char)
+        p.pos++
+    }
+
+    return output.String()
+}
@@ -0,0 +1,137 @@
+package iguana
+
+import (
+    "fmt"
+    "testing"
+)
+
+// the parts of a move found in a ZSS file that the tests check
+type zss_move struct {
+    name       string
+    value      string
+    commands   string
+    statetypes int
+}
+
+func TestScrapeZSS(t *testing.T) {
+    tests := []struct {
+        name  string
+        input string
+        want  []zss_move
+    }{
+        {
+            "plain if",
+            `
+[StateDef -1]
+# Fireball
+if command = "QCF_x" && statetype != A {
+    changeState{value: 1000}
+}`,
+            []zss_move{{"Fireball", "1000", "[[QCF_x]]", statetypes_ground | 1<<3}},
+        },
+        {
+            "else if and else",
+            `
+[StateDef -1]
+if command = "QCF_x" {
+    # Fireball
+    changeState{value: 1000}
+} else if command = "QCB_x" {
+    # Hurricane Kick
+    changeState{value: 1100}
+} else {
+    # No Command
+    changeState{value: 1200}
+}`,
+            []zss_move{{"Fireball", "1000", "[[QCF_x]]", statetypes_all}, {"Hurricane Kick", "1100", "[[QCB_x]]", statetypes_all}},
+        },
+        {
+            "else inside of an if",
+            `
+[StateDef -1]
+if command = "x" {
+    if statetype = A {
+        # Air Punch
+        changeState{value: 600}
+    } else {
+        # Punch
+        changeState{value: 200}
+    }
+}`,
+            []zss_move{{"Air Punch", "600", "[[x]]", statetypes_air}, {"Punch", "200", "[[x]]", statetypes_all &^ statetypes_air}},
+        },
+        {
+            "else if carries over the negated branch before it",
+            `
+[StateDef -1]
+if statetype = A {
+    ignorehitpause changeState{value: 0}
+} else if command = "y" {
+    changeState{value: 210}
+}`,
+            []zss_move{{"State 210", "210", "[[y]]", statetypes_all &^ statetypes_air}},
+        },
+        {
+            "other states and functions are skipped",
+            `
+[Function Test(a)]
+if command = "x" {
+    changeState{value: 200}
+}
+
+[StateDef -1]
+let x = 1;
+if command = "z" && ctrl {
+    # Kick
+    changeState{value: 220; ctrl: 0}
+}
+
+[StateDef 200]
+if command = "x" {
+    changeState{value: 210}
+}`,
+            []zss_move{{"Kick", "220", "[[z]]", statetypes_all}},
+        },
+    }
+
+    for _, test := range tests {
+        t.Run(test.name, func(t *testing.T) {
+            cv := new_converter(DefaultOptions())
+            moves := cv.scrape_zss("test.zss", test.input, make(map[int]int))
+
+            var got []zss_move
+            for _, move := range moves {
+                got = append(got, zss_move{move.name, move.value, fmt.Sprint(move.commands), move.statetypes})
+            }
+
+            if fmt.Sprint(got) != fmt.Sprint(test.want) {
+                t.Errorf("got moves %v, want %v", got, test.want)
+            }
+        })
+    }
+}
+
+func TestScrapeZSSCosts(t *testing.T) {
+    input := `
+[StateDef 3000; type: S; poweradd: -3000;]
+changeState{value: 3001}
+
+[StateDef 1000]
+if time = 0 {
+    powerAdd{value: -1000}
+} else {
+    powerAdd{value: -2 * 1000}
+}
+
+[StateDef 200]
+powerAdd{value: 50}
+`
+
+    costs := make(map[int]int)
+    new_converter(DefaultOptions()).scrape_zss("test.zss", input, costs)
+
+    want := map[int]int{3000: 3000, 1000: 2000}
+    if fmt.Sprint(costs) != fmt.Sprint(want) {
+        t.Errorf("got costs %v, want %v", costs, want)
+    }
+}